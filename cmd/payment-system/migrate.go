@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"payment-system/db/migrations"
+	"payment-system/internal/config"
+	"payment-system/internal/db"
+	"payment-system/internal/logging"
+	"payment-system/internal/migrate"
+)
+
+const migrateUsage = `usage: payment-system migrate up|down [steps]|status
+
+  up      apply every pending migration
+  down    revert the last steps migrations, one by default
+  status  print the schema version and pending migrations
+`
+
+// migrateDatabase applies the embedded migrations. Replicas and the subcommand take an advisory lock,
+// so they never migrate the same database at once.
+func migrateDatabase(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+	}
+	_ = flags.Parse(args)
+
+	steps := 1
+	command := flags.Arg(0)
+	switch {
+	case flags.NArg() == 1 && (command == "up" || command == "down" || command == "status"):
+	case flags.NArg() == 2 && command == "down":
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("steps %q is not a positive number", flags.Arg(1))
+		}
+		steps = n
+	default:
+		flags.Usage()
+		return statusError{code: 2}
+	}
+
+	cfg, err := config.Load("migrate", nil)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	database, err := db.New(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer database.Close()
+
+	migrator, err := migrate.New(database, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("loading migrations: %w", err)
+	}
+
+	ctx := logging.NewContext(context.Background(), logging.New(os.Stderr, logging.Redaction{}))
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("migrating up: %w", err)
+		}
+		fmt.Printf("applied %d migrations\n", len(applied))
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return fmt.Errorf("migrating down: %w", err)
+		}
+		fmt.Printf("reverted %d migrations\n", len(reverted))
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("getting migration status: %w", err)
+		}
+
+		fmt.Printf("version %d of %d", status.Version, status.Latest)
+		if status.Dirty {
+			fmt.Print(", dirty")
+		}
+		fmt.Println()
+		for _, m := range status.Pending {
+			fmt.Printf("pending %06d_%s\n", m.Version, m.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"payment-system/internal/config"
+	"payment-system/internal/db"
+	"payment-system/internal/reconciliation"
+	"payment-system/internal/storage"
+)
+
+// reconcile recomputes wallet balances from operations and ledger postings and writes the drift report.
+// It exits with status 2 when drifted wallets are found and not fixed, so it can be used in scripts.
+func reconcile(args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	format := flags.String("format", "json", "report format: json or csv")
+	output := flags.String("output", "", "report file, stdout by default")
+	fix := flags.Bool("fix", false, "record adjustment entries for drifted wallets")
+	reason := flags.String("reason", "", "audit reason of adjustment entries, required with -fix")
+	_ = flags.Parse(args)
+
+	write := reconciliation.WriteJSON
+	switch *format {
+	case "json":
+	case "csv":
+		write = reconciliation.WriteCSV
+	default:
+		return fmt.Errorf("unknown report format %q", *format)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("creating report file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	cfg, err := config.Load("reconcile", nil)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	database, err := db.New(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer database.Close()
+
+	reconciler := reconciliation.New(storage.New(database))
+	ctx := context.Background()
+
+	var report reconciliation.Report
+	if *fix {
+		report, err = reconciler.Fix(ctx, *reason)
+	} else {
+		report, err = reconciler.Check(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("reconciling balances: %w", err)
+	}
+
+	if err := write(out, report); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+
+	if !*fix && len(report.Drifts) > 0 {
+		return statusError{code: 2}
+	}
+	return nil
+}
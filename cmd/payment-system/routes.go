@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+
+	"payment-system/internal/handlers/add_wallet"
+	"payment-system/internal/handlers/authorize_hold"
+	"payment-system/internal/handlers/capture_hold"
+	"payment-system/internal/handlers/deposit_money"
+	"payment-system/internal/handlers/get_balance"
+	"payment-system/internal/handlers/get_operations"
+	"payment-system/internal/handlers/get_quote"
+	"payment-system/internal/handlers/get_transaction"
+	"payment-system/internal/handlers/refund_transaction"
+	"payment-system/internal/handlers/transfer_money"
+	"payment-system/internal/handlers/void_hold"
+	"payment-system/internal/handlers/withdraw_money"
+	"payment-system/internal/health"
+	"payment-system/internal/idempotency"
+	"payment-system/internal/metrics"
+	"payment-system/internal/router"
+	"payment-system/internal/wallet"
+)
+
+// routes serves the resource API under /v1. The RPC-style endpoints of before are aliases of the same
+// handlers, they are kept until clients moved to /v1.
+func routes(mux *http.ServeMux, walletService *wallet.Service, idempotent *idempotency.Middleware,
+	probes *health.Health, m *metrics.Metrics) {
+	// money moves are refused while draining, reads are served until the server stops.
+	// Their idempotency keys are unique per wallet, hold or transaction like the keys of operations.
+	moneyMove := func(endpoint string, handler http.Handler, scope string) http.Handler {
+		return probes.RejectWhileShuttingDown(idempotent.Wrap(endpoint, handler, scope))
+	}
+	var (
+		addWallet      = idempotent.Wrap("addWallet", add_wallet.NewHandler(walletService))
+		depositMoney   = moneyMove("depositMoney", deposit_money.NewHandler(walletService), "wallet_id")
+		withdrawMoney  = moneyMove("withdrawMoney", withdraw_money.NewHandler(walletService), "wallet_id")
+		transferMoney  = moneyMove("transferMoney", transfer_money.NewHandler(walletService), "from_wallet_id")
+		authorizeHold  = moneyMove("authorizeHold", authorize_hold.NewHandler(walletService), "from_wallet_id")
+		captureHold    = moneyMove("captureHold", capture_hold.NewHandler(walletService), "hold_id")
+		voidHold       = moneyMove("voidHold", void_hold.NewHandler(walletService), "hold_id")
+		refund         = moneyMove("refundTransaction", refund_transaction.NewHandler(walletService), "transaction_id")
+		getBalance     = get_balance.NewHandler(walletService)
+		getOperations  = get_operations.NewHandler(walletService)
+		getQuote       = get_quote.NewHandler(walletService)
+		getTransaction = get_transaction.NewHandler(walletService)
+	)
+
+	v1 := router.New()
+	route := func(method, pattern string, handler http.Handler, fields ...router.Field) {
+		if len(fields) > 0 {
+			handler = router.WithFields(handler, fields...)
+		}
+		v1.Handle(method, pattern, m.Instrument(pattern, handler))
+	}
+	walletID := router.IntField("id", "wallet_id")
+	transactionID := router.IntField("id", "transaction_id")
+	holdID := router.IntField("id", "hold_id")
+
+	route(http.MethodPost, "/v1/wallets", addWallet)
+	route(http.MethodGet, "/v1/wallets/{id}", getBalance, walletID)
+	route(http.MethodGet, "/v1/wallets/{id}/operations", getOperations, walletID,
+		router.StringField("date", "date"),
+		router.StringField("from", "from"),
+		router.StringField("to", "to"),
+		router.IntField("direction", "direction"),
+		router.IntField("limit", "limit"),
+		router.StringField("cursor", "cursor"),
+		router.StringField("format", "format"),
+	)
+	route(http.MethodPost, "/v1/wallets/{id}/deposits", depositMoney, walletID)
+	route(http.MethodPost, "/v1/wallets/{id}/withdrawals", withdrawMoney, walletID)
+	route(http.MethodPost, "/v1/transfers", transferMoney)
+	route(http.MethodGet, "/v1/transactions/{id}", getTransaction, transactionID)
+	route(http.MethodPost, "/v1/transactions/{id}/refunds", refund, transactionID)
+	route(http.MethodPost, "/v1/holds", authorizeHold)
+	route(http.MethodPost, "/v1/holds/{id}/capture", captureHold, holdID)
+	route(http.MethodPost, "/v1/holds/{id}/void", voidHold, holdID)
+	route(http.MethodPost, "/v1/quotes", getQuote)
+	mux.Handle("/v1/", v1)
+
+	alias := func(path string, handler http.Handler) {
+		mux.Handle(path, m.Instrument(path, handler))
+	}
+	alias("/addWallet", addWallet)
+	alias("/depositMoney", depositMoney)
+	alias("/withdrawMoney", withdrawMoney)
+	alias("/transferMoney", transferMoney)
+	alias("/authorizeHold", authorizeHold)
+	alias("/captureHold", captureHold)
+	alias("/voidHold", voidHold)
+	alias("/refundTransaction", refund)
+	alias("/getBalance", getBalance)
+	alias("/getOperations", getOperations)
+	alias("/getQuote", getQuote)
+	alias("/getTransaction", getTransaction)
+}
@@ -2,52 +2,274 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+
+	"payment-system/db/migrations"
+	"payment-system/internal/config"
 	"payment-system/internal/db"
-	"payment-system/internal/handlers/add_wallet"
-	"payment-system/internal/handlers/deposit_money"
-	"payment-system/internal/handlers/get_operations"
-	"payment-system/internal/handlers/transfer_money"
+	"payment-system/internal/fx"
+	"payment-system/internal/health"
+	"payment-system/internal/idempotency"
+	"payment-system/internal/logging"
+	"payment-system/internal/metrics"
+	"payment-system/internal/migrate"
+	"payment-system/internal/reconciliation"
+	"payment-system/internal/router"
+	"payment-system/internal/spanlog"
 	"payment-system/internal/storage"
+	"payment-system/internal/storage/memory"
 	"payment-system/internal/wallet"
 )
 
 func main() {
-	port, ok := os.LookupEnv("PORT")
-	if !ok {
-		port = "8080"
+	os.Exit(exitCode(dispatch(os.Args[1:])))
+}
+
+// dispatch runs the subcommand named by the first argument, the service without one.
+func dispatch(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "reconcile":
+			return reconcile(args[1:])
+		case "migrate":
+			return migrateDatabase(args[1:])
+		}
+	}
+
+	cfg, err := config.Load("payment-system", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return statusError{code: 2, err: err}
+	}
+
+	return run(cfg)
+}
+
+// statusError ends the process with code instead of 1, err is printed unless it is nil.
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e statusError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e statusError) Unwrap() error {
+	return e.err
+}
+
+// exitCode prints err and returns the status the process exits with. Commands return their errors
+// instead of exiting, so their deferred cleanup runs before the process ends.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var status statusError
+	if errors.As(err, &status) {
+		if status.err != nil {
+			fmt.Fprintln(os.Stderr, status.err)
+		}
+		return status.code
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
+func run(cfg config.Config) error {
+	logger := logging.New(os.Stdout, logging.Redaction{
+		Amounts: cfg.Logging.RedactAmounts,
+		Keys:    cfg.Logging.RedactKeys,
+	})
+	m := metrics.New()
+	options := []wallet.Option{
+		wallet.WithHoldTTL(cfg.Features.Holds.TTL.Duration()),
+		wallet.WithObserver(m),
+	}
+	if cfg.Features.FX.Enabled {
+		rates, err := fx.LoadFile(cfg.Features.FX.RatesFile)
+		if err != nil {
+			return fmt.Errorf("loading fx rates: %w", err)
+		}
+
+		options = append(options, wallet.WithFXRates(rates, cfg.Features.FX.QuoteTTL.Duration()))
 	}
 
-	database := db.New()
-	walletService := wallet.New(storage.New(database))
+	if len(cfg.Limits.MaxAmounts) > 0 {
+		options = append(options, wallet.WithMaxAmounts(cfg.Limits.MaxAmounts))
+	}
 
-	srv := http.Server{Addr: fmt.Sprintf(":%s", port)}
-	http.Handle("/addWallet", add_wallet.NewHandler(walletService))
-	http.Handle("/depositMoney", deposit_money.NewHandler(walletService))
-	http.Handle("/transferMoney", transfer_money.NewHandler(walletService))
-	http.Handle("/getOperations", get_operations.NewHandler(walletService))
+	idempotencyWindow := cfg.Limits.IdempotencyWindow.Duration()
+	idempotencyOptions := []idempotency.Option{
+		idempotency.WithObserver(m),
+		idempotency.WithPendingTimeout(cfg.Limits.IdempotencyPendingTimeout.Duration()),
+	}
+	var walletService *wallet.Service
+	var idempotent *idempotency.Middleware
+	var pgStorage *storage.Storage
+	probes := health.New()
+	if cfg.Storage == config.StorageMemory {
+		memoryStorage := memory.New()
+		walletService = wallet.New(memoryStorage, options...)
+		idempotent = idempotency.New(memoryStorage, idempotencyWindow, idempotencyOptions...)
+	} else {
+		database, err := db.New(cfg.DB)
+		if err != nil {
+			return fmt.Errorf("connecting to database: %w", err)
+		}
+		defer database.Close()
+		m.RegisterDBStats(database.DB)
 
+		migrator, err := migrate.New(database, migrations.FS)
+		if err != nil {
+			return fmt.Errorf("loading migrations: %w", err)
+		}
+		if cfg.DB.AutoMigrate {
+			if _, err := migrator.Up(logging.NewContext(context.Background(), logger)); err != nil {
+				return fmt.Errorf("migrating database: %w", err)
+			}
+		}
+		addDatabaseChecks(probes, database, migrator)
+
+		pgStorage = storage.New(database)
+		walletService = wallet.New(pgStorage, options...)
+		idempotent = idempotency.New(pgStorage, idempotencyWindow, idempotencyOptions...)
+	}
+
+	tracer, closeTracer, err := newTracer(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("creating tracer: %w", err)
+	}
+	defer closeTracer()
+
+	jobCtx, stopJobs := context.WithCancel(logging.NewContext(spanlog.NewContext(context.Background(), tracer), logger))
+	defer stopJobs()
+	go walletService.SweepHolds(jobCtx, cfg.Features.Holds.SweepInterval.Duration())
+	go idempotent.SweepKeys(jobCtx, cfg.Limits.IdempotencySweepInterval.Duration())
+	if cfg.Features.Reconciliation.Enabled {
+		go reconciliation.New(pgStorage).Run(jobCtx, cfg.Features.Reconciliation.Interval.Duration())
+	}
+
+	srv := http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.HTTP.Port),
+		ReadTimeout:       cfg.HTTP.ReadTimeout.Duration(),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout.Duration(),
+		WriteTimeout:      cfg.HTTP.WriteTimeout.Duration(),
+		IdleTimeout:       cfg.HTTP.IdleTimeout.Duration(),
+	}
+	mux := http.NewServeMux()
+	// the body is read and bounded once before the middlewares and handlers which buffer it
+	handler := router.LimitBody(int64(cfg.HTTP.MaxBodyBytes), mux)
+	if tracer != nil {
+		handler = spanlog.Middleware(tracer, handler)
+	}
+	srv.Handler = logging.AccessLog(logger, handler)
+	routes(mux, walletService, idempotent, probes, m)
+	mux.Handle("/metrics", m.Handler())
+	mux.Handle("/healthz", probes.Liveness())
+	mux.Handle("/readyz", probes.Readiness())
+
+	serveErr := make(chan error, 1)
 	go func() {
-		log.Printf("listening on port %s\n", port)
+		logger.Info("listening", "port", cfg.HTTP.Port)
 		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Fatalf("failed to listen and serve: %s", err)
+			serveErr <- err
 		}
 	}()
 
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	<-stop
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("listening and serving: %w", err)
+	case sig := <-stop:
+		logger.Info("draining", "signal", sig.String(), "drain_period", cfg.DrainPeriod.Duration().String())
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// readiness fails and money moves are refused, a second signal skips the rest of the drain period
+	probes.Shutdown()
+	select {
+	case <-time.After(cfg.DrainPeriod.Duration()):
+	case <-stop:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration())
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("failed to shutdown server: %s", err)
+	logger.Info("shutting down")
+	shutdownErr := srv.Shutdown(ctx)
+	stopJobs()
+
+	// the database is closed by a deferred call, so transactions of jobs and of requests cut off by the shutdown
+	// timeout are still given their time to commit or roll back
+	if pgStorage != nil {
+		txCtx, cancelTx := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration())
+		defer cancelTx()
+
+		if err := pgStorage.Wait(txCtx); err != nil {
+			return fmt.Errorf("waiting for open transactions: %w", err)
+		}
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("shutting down server: %w", shutdownErr)
+	}
+
+	logger.Info("stopped")
+	return nil
+}
+
+// addDatabaseChecks makes the service ready only while the database is reachable and its schema
+// is at the version of the last embedded migration.
+func addDatabaseChecks(probes *health.Health, database *sqlx.DB, migrator *migrate.Migrator) {
+	probes.AddCheck("database", func(ctx context.Context) error {
+		return database.PingContext(ctx)
+	})
+	probes.AddCheck("migrations", func(ctx context.Context) error {
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return fmt.Errorf("migration %d is dirty", status.Version)
+		}
+		if status.Version != status.Latest {
+			return fmt.Errorf("schema version is %d, expected %d", status.Version, status.Latest)
+		}
+
+		return nil
+	})
+}
+
+// newTracer returns nil when tracing is off. The returned func closes the file spans are written to.
+func newTracer(cfg config.Tracing) (*spanlog.Tracer, func(), error) {
+	switch cfg.Exporter {
+	case config.TracingStdout:
+		return spanlog.New(spanlog.NewWriterExporter(os.Stdout)), func() {}, nil
+	case config.TracingFile:
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening tracing file: %w", err)
+		}
+
+		return spanlog.New(spanlog.NewWriterExporter(f)), func() { f.Close() }, nil
+	default:
+		return nil, func() {}, nil
 	}
 }
@@ -0,0 +1 @@
+ALTER TABLE wallet ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
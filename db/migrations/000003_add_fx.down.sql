@@ -0,0 +1,8 @@
+ALTER TABLE operation
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS source_value,
+    DROP COLUMN IF EXISTS source_currency,
+    DROP COLUMN IF EXISTS destination_value,
+    DROP COLUMN IF EXISTS destination_currency;
+
+DROP TABLE IF EXISTS fx_quote;
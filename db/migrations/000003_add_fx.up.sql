@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS fx_quote(
+    id VARCHAR(36) PRIMARY KEY,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT rate_positive CHECK (rate > 0)
+);
+
+ALTER TABLE operation
+    ADD COLUMN IF NOT EXISTS fx_rate NUMERIC,
+    ADD COLUMN IF NOT EXISTS source_value BIGINT,
+    ADD COLUMN IF NOT EXISTS source_currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS destination_value BIGINT,
+    ADD COLUMN IF NOT EXISTS destination_currency CHAR(3);
@@ -0,0 +1 @@
+ALTER TABLE wallet DROP COLUMN IF EXISTS updated_at;
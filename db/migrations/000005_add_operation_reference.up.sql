@@ -0,0 +1 @@
+ALTER TABLE operation ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
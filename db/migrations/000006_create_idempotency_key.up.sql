@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_key(
+    endpoint VARCHAR(64) NOT NULL,
+    key VARCHAR(36) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code SMALLINT,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (endpoint, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx
+    ON idempotency_key(expires_at);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS operation_wallet_id_date_idx;
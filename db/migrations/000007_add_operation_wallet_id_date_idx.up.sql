@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS operation_wallet_id_date_idx
+    ON operation(wallet_id, date, idempotency_key);
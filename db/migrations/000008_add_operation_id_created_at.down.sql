@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS operation_wallet_id_created_at_idx;
+CREATE INDEX IF NOT EXISTS operation_wallet_id_date_idx
+    ON operation(wallet_id, date, idempotency_key);
+
+ALTER TABLE operation
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS id;
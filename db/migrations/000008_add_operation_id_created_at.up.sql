@@ -0,0 +1,13 @@
+ALTER TABLE operation
+    ADD COLUMN IF NOT EXISTS id BIGSERIAL PRIMARY KEY,
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+
+UPDATE operation SET created_at = date::TIMESTAMPTZ WHERE created_at IS NULL;
+
+ALTER TABLE operation
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN created_at SET NOT NULL;
+
+DROP INDEX IF EXISTS operation_wallet_id_date_idx;
+CREATE INDEX IF NOT EXISTS operation_wallet_id_created_at_idx
+    ON operation(wallet_id, created_at, id);
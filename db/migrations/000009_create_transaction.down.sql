@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS operation_transaction_id_idx;
+
+ALTER TABLE operation
+    DROP COLUMN IF EXISTS counterparty_wallet_id,
+    DROP COLUMN IF EXISTS transaction_id;
+
+DROP TABLE IF EXISTS transaction;
@@ -0,0 +1,70 @@
+CREATE TABLE IF NOT EXISTS transaction(
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    initiator VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(36) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE operation
+    ADD COLUMN IF NOT EXISTS transaction_id BIGINT,
+    ADD COLUMN IF NOT EXISTS counterparty_wallet_id BIGINT;
+
+-- legs of a transfer were only linked by the shared idempotency key, which is unique per wallet only.
+-- A withdrawal and a deposit are legs of one transfer when they were inserted together with the same key
+-- on other wallets, the deposit got the destination value and both got the same exchange.
+CREATE TEMPORARY TABLE transfer_leg_pair ON COMMIT DROP AS
+SELECT w.id AS withdrawal_id, d.id AS deposit_id, w.wallet_id AS from_wallet_id, d.wallet_id AS to_wallet_id,
+       w.idempotency_key, w.created_at, NULL::BIGINT AS transaction_id
+FROM operation w
+JOIN operation d ON d.idempotency_key = w.idempotency_key
+    AND d.created_at = w.created_at
+    AND d.date IS NOT DISTINCT FROM w.date
+    AND d.wallet_id <> w.wallet_id
+    AND d.direction = 0
+    AND d.value = coalesce(w.destination_value, w.value)
+    AND d.fx_rate IS NOT DISTINCT FROM w.fx_rate
+    AND d.source_value IS NOT DISTINCT FROM w.source_value
+    AND d.source_currency IS NOT DISTINCT FROM w.source_currency
+    AND d.destination_value IS NOT DISTINCT FROM w.destination_value
+    AND d.destination_currency IS NOT DISTINCT FROM w.destination_currency
+WHERE w.direction = 1 AND w.transaction_id IS NULL AND d.transaction_id IS NULL;
+
+-- a leg which pairs with more than one other leg can not be told apart, it stays a single operation
+DELETE FROM transfer_leg_pair p
+WHERE EXISTS (
+    SELECT 1 FROM transfer_leg_pair other
+    WHERE (other.withdrawal_id = p.withdrawal_id) <> (other.deposit_id = p.deposit_id)
+);
+
+UPDATE transfer_leg_pair SET transaction_id = nextval('transaction_id_seq');
+
+INSERT INTO transaction(id, type, status, initiator, idempotency_key, created_at)
+SELECT transaction_id, 'transfer', 'completed', 'unknown', idempotency_key, created_at
+FROM transfer_leg_pair;
+
+UPDATE operation o SET transaction_id = p.transaction_id, counterparty_wallet_id = p.to_wallet_id
+FROM transfer_leg_pair p
+WHERE o.id = p.withdrawal_id;
+
+UPDATE operation o SET transaction_id = p.transaction_id, counterparty_wallet_id = p.from_wallet_id
+FROM transfer_leg_pair p
+WHERE o.id = p.deposit_id;
+
+-- the rest are single deposits and withdrawals
+UPDATE operation SET transaction_id = nextval('transaction_id_seq') WHERE transaction_id IS NULL;
+
+INSERT INTO transaction(id, type, status, initiator, idempotency_key, created_at)
+SELECT transaction_id, CASE direction WHEN 0 THEN 'deposit' ELSE 'withdrawal' END, 'completed', 'unknown',
+       idempotency_key, created_at
+FROM operation
+WHERE NOT EXISTS (SELECT 1 FROM transaction t WHERE t.id = operation.transaction_id);
+
+ALTER TABLE operation
+    ALTER COLUMN transaction_id SET NOT NULL,
+    ADD CONSTRAINT fk_transaction FOREIGN KEY(transaction_id) REFERENCES transaction(id),
+    ADD CONSTRAINT fk_counterparty_wallet FOREIGN KEY(counterparty_wallet_id) REFERENCES wallet(id);
+
+CREATE INDEX IF NOT EXISTS operation_transaction_id_idx
+    ON operation(transaction_id);
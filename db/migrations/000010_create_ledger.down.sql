@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS journal_entry_balanced ON posting;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+DROP TABLE IF EXISTS posting;
+DROP TABLE IF EXISTS journal_entry;
+DROP TABLE IF EXISTS account;
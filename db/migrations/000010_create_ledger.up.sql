@@ -0,0 +1,87 @@
+-- an account belongs either to a wallet or to the system, e.g. the source of deposits
+CREATE TABLE IF NOT EXISTS account(
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id BIGINT UNIQUE,
+    code VARCHAR(32),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_account_wallet FOREIGN KEY(wallet_id) REFERENCES wallet(id),
+    CONSTRAINT wallet_or_system CHECK ((wallet_id IS NULL) <> (code IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS account_code_currency_unique_idx
+    ON account(code, currency) WHERE code IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS journal_entry(
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT,
+    description VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_journal_entry_transaction FOREIGN KEY(transaction_id) REFERENCES transaction(id)
+);
+
+-- amount is signed minor units of the account currency, positive amounts increase the account balance
+CREATE TABLE IF NOT EXISTS posting(
+    id BIGSERIAL PRIMARY KEY,
+    journal_entry_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    CONSTRAINT fk_posting_journal_entry FOREIGN KEY(journal_entry_id) REFERENCES journal_entry(id),
+    CONSTRAINT fk_posting_account FOREIGN KEY(account_id) REFERENCES account(id),
+    CONSTRAINT amount_non_zero CHECK (amount <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS posting_journal_entry_id_idx
+    ON posting(journal_entry_id);
+CREATE INDEX IF NOT EXISTS posting_account_id_idx
+    ON posting(account_id);
+
+-- postings of a journal entry must sum to zero in every currency when the storage transaction commits
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM posting
+        WHERE journal_entry_id = NEW.journal_entry_id
+        GROUP BY currency
+        HAVING sum(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.journal_entry_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'journal_entry_balanced';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS journal_entry_balanced ON posting;
+CREATE CONSTRAINT TRIGGER journal_entry_balanced
+    AFTER INSERT OR UPDATE ON posting
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE PROCEDURE check_journal_entry_balanced();
+
+INSERT INTO account(wallet_id, currency)
+SELECT id, currency FROM wallet
+ON CONFLICT DO NOTHING;
+
+INSERT INTO account(code, currency)
+SELECT code, currency
+FROM (VALUES ('deposits'), ('withdrawals'), ('fees'), ('fx'), ('opening_balances')) AS codes(code)
+CROSS JOIN (SELECT DISTINCT currency FROM wallet UNION SELECT 'USD') AS currencies
+ON CONFLICT DO NOTHING;
+
+-- existing balances are opened against the system, so the ledger matches the wallets from the start
+INSERT INTO journal_entry(description)
+SELECT 'opening balance of wallet ' || id FROM wallet WHERE value <> 0;
+
+INSERT INTO posting(journal_entry_id, account_id, amount, currency)
+SELECT j.id, a.id, w.value, w.currency
+FROM wallet w
+JOIN account a ON a.wallet_id = w.id
+JOIN journal_entry j ON j.description = 'opening balance of wallet ' || w.id
+WHERE w.value <> 0
+UNION ALL
+SELECT j.id, s.id, -w.value, w.currency
+FROM wallet w
+JOIN account s ON s.code = 'opening_balances' AND s.currency = w.currency
+JOIN journal_entry j ON j.description = 'opening balance of wallet ' || w.id
+WHERE w.value <> 0;
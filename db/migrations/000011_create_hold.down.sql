@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS hold;
+
+ALTER TABLE wallet
+    DROP CONSTRAINT IF EXISTS held_within_value,
+    DROP COLUMN IF EXISTS held;
@@ -0,0 +1,30 @@
+-- held is the part of the wallet value reserved by authorized holds, it is not available for spending
+ALTER TABLE wallet
+    ADD COLUMN IF NOT EXISTS held BIGINT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT held_within_value CHECK (held >= 0 AND held <= value);
+
+CREATE TABLE IF NOT EXISTS hold(
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    from_wallet_id BIGINT NOT NULL,
+    to_wallet_id BIGINT NOT NULL,
+    value BIGINT NOT NULL,
+    captured_value BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    idempotency_key VARCHAR(36) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_hold_transaction FOREIGN KEY(transaction_id) REFERENCES transaction(id),
+    CONSTRAINT fk_hold_from_wallet FOREIGN KEY(from_wallet_id) REFERENCES wallet(id),
+    CONSTRAINT fk_hold_to_wallet FOREIGN KEY(to_wallet_id) REFERENCES wallet(id),
+    CONSTRAINT hold_value_positive CHECK (value > 0),
+    CONSTRAINT captured_within_hold CHECK (captured_value >= 0 AND captured_value <= value)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS hold_idempotency_key_from_wallet_id_unique_idx
+    ON hold(idempotency_key, from_wallet_id);
+
+CREATE INDEX IF NOT EXISTS hold_authorized_expires_at_idx
+    ON hold(expires_at) WHERE status = 'authorized';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS transaction_original_transaction_id_idx;
+
+UPDATE transaction SET status = 'completed' WHERE status IN ('refunded', 'partially_refunded') AND type = 'transfer';
+UPDATE transaction SET status = 'captured' WHERE status IN ('refunded', 'partially_refunded');
+
+ALTER TABLE transaction
+    DROP COLUMN IF EXISTS refunded_value,
+    DROP COLUMN IF EXISTS original_transaction_id,
+    ALTER COLUMN status TYPE VARCHAR(16);
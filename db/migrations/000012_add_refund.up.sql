@@ -0,0 +1,10 @@
+-- refunded_value is in minor units of the wallet which received the original transaction
+ALTER TABLE transaction
+    ALTER COLUMN status TYPE VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS original_transaction_id BIGINT,
+    ADD COLUMN IF NOT EXISTS refunded_value BIGINT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT fk_original_transaction FOREIGN KEY(original_transaction_id) REFERENCES transaction(id),
+    ADD CONSTRAINT refunded_value_non_negative CHECK (refunded_value >= 0);
+
+CREATE INDEX IF NOT EXISTS transaction_original_transaction_id_idx
+    ON transaction(original_transaction_id) WHERE original_transaction_id IS NOT NULL;
@@ -0,0 +1,10 @@
+DELETE FROM idempotency_key k
+    USING idempotency_key other
+    WHERE k.endpoint = other.endpoint AND k.key = other.key AND k.scope > other.scope;
+
+ALTER TABLE idempotency_key
+    DROP CONSTRAINT idempotency_key_pkey,
+    ADD PRIMARY KEY (endpoint, key);
+
+ALTER TABLE idempotency_key
+    DROP COLUMN IF EXISTS scope;
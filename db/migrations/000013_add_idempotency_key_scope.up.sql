@@ -0,0 +1,8 @@
+-- scope is the wallet, hold or transaction the request acts on, a key is unique within it
+-- like the idempotency key of an operation is unique per wallet
+ALTER TABLE idempotency_key
+    ADD COLUMN IF NOT EXISTS scope VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_key
+    DROP CONSTRAINT idempotency_key_pkey,
+    ADD PRIMARY KEY (endpoint, scope, key);
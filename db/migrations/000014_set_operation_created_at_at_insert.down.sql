@@ -0,0 +1,3 @@
+ALTER TABLE operation
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN date SET DEFAULT now()::DATE;
@@ -0,0 +1,6 @@
+-- created_at is taken when the operation is inserted instead of when its transaction began. Writers lock
+-- the wallet before they insert its operations, so they are ordered like they commit and a keyset page
+-- of the wallet is not passed by an operation which commits later. Dates are UTC like the filters.
+ALTER TABLE operation
+    ALTER COLUMN created_at SET DEFAULT clock_timestamp(),
+    ALTER COLUMN date SET DEFAULT (clock_timestamp() AT TIME ZONE 'UTC')::DATE;
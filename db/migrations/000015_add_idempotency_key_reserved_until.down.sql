@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_key
+    DROP COLUMN IF EXISTS reserved_until;
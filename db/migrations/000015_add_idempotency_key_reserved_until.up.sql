@@ -0,0 +1,7 @@
+-- a pending key whose request did not complete until reserved_until, e.g. because the process was killed,
+-- may be reserved again by a retry
+ALTER TABLE idempotency_key
+    ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE idempotency_key
+    ALTER COLUMN reserved_until DROP DEFAULT;
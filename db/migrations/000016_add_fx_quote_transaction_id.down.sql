@@ -0,0 +1,2 @@
+ALTER TABLE fx_quote
+    DROP COLUMN IF EXISTS transaction_id;
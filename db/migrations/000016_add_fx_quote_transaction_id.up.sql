@@ -0,0 +1,4 @@
+-- a locked quote is used by the one transfer it is recorded with, later transfers are refused
+ALTER TABLE fx_quote
+    ADD COLUMN IF NOT EXISTS transaction_id BIGINT,
+    ADD CONSTRAINT fk_quote_transaction FOREIGN KEY(transaction_id) REFERENCES transaction(id);
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the schema into the binary.
+package migrations
+
+import "embed"
+
+// FS holds files named like 000001_create_tables.up.sql and 000001_create_tables.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
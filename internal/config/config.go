@@ -0,0 +1,451 @@
+// Package config loads settings of the service. Defaults are overridden by a JSON file, the file by
+// environment variables and the environment by command line flags. The database connection itself is
+// configured by the libpq PG* environment variables.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"payment-system/internal/money"
+)
+
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingFile   = "file"
+)
+
+type Config struct {
+	HTTP            HTTP     `json:"http"`
+	DB              DB       `json:"db"`
+	Storage         string   `json:"storage"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	DrainPeriod     Duration `json:"drain_period"`
+	Features        Features `json:"features"`
+	Limits          Limits   `json:"limits"`
+	Logging         Logging  `json:"logging"`
+	Tracing         Tracing  `json:"tracing"`
+}
+
+type HTTP struct {
+	Port              int      `json:"port"`
+	ReadTimeout       Duration `json:"read_timeout"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout"`
+	// MaxBodyBytes bounds request bodies, longer ones are answered with 413.
+	MaxBodyBytes int `json:"max_body_bytes"`
+}
+
+// DB configures the connection pool, zero means no limit. AutoMigrate applies pending migrations on start.
+type DB struct {
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time"`
+	AutoMigrate     bool     `json:"auto_migrate"`
+}
+
+type Features struct {
+	FX             FX             `json:"fx"`
+	Holds          Holds          `json:"holds"`
+	Reconciliation Reconciliation `json:"reconciliation"`
+}
+
+// FX enables cross-currency transfers with rates of the file.
+type FX struct {
+	Enabled   bool     `json:"enabled"`
+	RatesFile string   `json:"rates_file"`
+	QuoteTTL  Duration `json:"quote_ttl"`
+}
+
+type Holds struct {
+	TTL           Duration `json:"ttl"`
+	SweepInterval Duration `json:"sweep_interval"`
+}
+
+// Reconciliation enables the periodic balance check.
+type Reconciliation struct {
+	Enabled  bool     `json:"enabled"`
+	Interval Duration `json:"interval"`
+}
+
+type Limits struct {
+	// MaxAmounts limits a single money move by its currency, currencies without an entry are not limited.
+	MaxAmounts        map[money.Currency]money.Amount `json:"max_amounts"`
+	IdempotencyWindow Duration                        `json:"idempotency_window"`
+	// IdempotencyPendingTimeout is how long a request may run before a retry with its key is served again.
+	IdempotencyPendingTimeout Duration `json:"idempotency_pending_timeout"`
+	// IdempotencySweepInterval is how often expired idempotency keys are deleted.
+	IdempotencySweepInterval Duration `json:"idempotency_sweep_interval"`
+}
+
+// Logging hides money amounts and idempotency keys in logs.
+type Logging struct {
+	RedactAmounts bool `json:"redact_amounts"`
+	RedactKeys    bool `json:"redact_keys"`
+}
+
+// Tracing writes spans as JSON lines to stdout or a file, it is meant for local use.
+type Tracing struct {
+	Exporter string `json:"exporter"`
+	File     string `json:"file"`
+}
+
+// Duration is encoded in JSON as a string like "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration should be a string: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Port:              8080,
+			ReadTimeout:       Duration(10 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			MaxBodyBytes:      1 << 20,
+		},
+		DB: DB{
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+		},
+		Storage:         StoragePostgres,
+		ShutdownTimeout: Duration(5 * time.Second),
+		DrainPeriod:     Duration(5 * time.Second),
+		Features: Features{
+			FX:             FX{QuoteTTL: Duration(30 * time.Second)},
+			Holds:          Holds{TTL: Duration(7 * 24 * time.Hour), SweepInterval: Duration(time.Minute)},
+			Reconciliation: Reconciliation{Interval: Duration(time.Hour)},
+		},
+		Limits: Limits{
+			IdempotencyWindow:         Duration(24 * time.Hour),
+			IdempotencyPendingTimeout: Duration(time.Minute),
+			IdempotencySweepInterval:  Duration(time.Hour),
+		},
+		Tracing: Tracing{Exporter: TracingNone},
+	}
+}
+
+// setting is a single option which can be set by a flag and an environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// settings are applied in this order, so a toggle goes after the options which enable it implicitly.
+var settings = []setting{
+	{flag: "port", env: "PORT", usage: "HTTP port", set: func(c *Config, v string) error {
+		return setInt(&c.HTTP.Port, v)
+	}},
+	{flag: "http-read-timeout", env: "HTTP_READ_TIMEOUT", usage: "timeout of reading a request", set: func(c *Config, v string) error {
+		return setDuration(&c.HTTP.ReadTimeout, v)
+	}},
+	{flag: "http-read-header-timeout", env: "HTTP_READ_HEADER_TIMEOUT", usage: "timeout of reading request headers", set: func(c *Config, v string) error {
+		return setDuration(&c.HTTP.ReadHeaderTimeout, v)
+	}},
+	{flag: "http-write-timeout", env: "HTTP_WRITE_TIMEOUT", usage: "timeout of writing a response", set: func(c *Config, v string) error {
+		return setDuration(&c.HTTP.WriteTimeout, v)
+	}},
+	{flag: "http-idle-timeout", env: "HTTP_IDLE_TIMEOUT", usage: "timeout of an idle keep-alive connection", set: func(c *Config, v string) error {
+		return setDuration(&c.HTTP.IdleTimeout, v)
+	}},
+	{flag: "http-max-body-bytes", env: "HTTP_MAX_BODY_BYTES", usage: "longest request body in bytes", set: func(c *Config, v string) error {
+		return setInt(&c.HTTP.MaxBodyBytes, v)
+	}},
+	{flag: "db-max-open-conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum of open database connections", set: func(c *Config, v string) error {
+		return setInt(&c.DB.MaxOpenConns, v)
+	}},
+	{flag: "db-max-idle-conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum of idle database connections", set: func(c *Config, v string) error {
+		return setInt(&c.DB.MaxIdleConns, v)
+	}},
+	{flag: "db-conn-max-lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum lifetime of a database connection", set: func(c *Config, v string) error {
+		return setDuration(&c.DB.ConnMaxLifetime, v)
+	}},
+	{flag: "db-conn-max-idle-time", env: "DB_CONN_MAX_IDLE_TIME", usage: "maximum idle time of a database connection", set: func(c *Config, v string) error {
+		return setDuration(&c.DB.ConnMaxIdleTime, v)
+	}},
+	{flag: "auto-migrate", env: "AUTO_MIGRATE", usage: "apply pending migrations on start", set: func(c *Config, v string) error {
+		return setBool(&c.DB.AutoMigrate, v)
+	}},
+	{flag: "storage", env: "STORAGE", usage: "wallet storage: postgres or memory", set: func(c *Config, v string) error {
+		c.Storage = v
+		return nil
+	}},
+	{flag: "shutdown-timeout", env: "SHUTDOWN_TIMEOUT", usage: "timeout of graceful shutdown", set: func(c *Config, v string) error {
+		return setDuration(&c.ShutdownTimeout, v)
+	}},
+	{flag: "drain-period", env: "DRAIN_PERIOD", usage: "time money moves are refused before the server stops", set: func(c *Config, v string) error {
+		return setDuration(&c.DrainPeriod, v)
+	}},
+	{flag: "fx-rates-file", env: "FX_RATES_FILE", usage: "exchange rates file, enables fx", set: func(c *Config, v string) error {
+		c.Features.FX.RatesFile = v
+		c.Features.FX.Enabled = v != ""
+		return nil
+	}},
+	{flag: "fx-enabled", env: "FX_ENABLED", usage: "enable cross-currency transfers", set: func(c *Config, v string) error {
+		return setBool(&c.Features.FX.Enabled, v)
+	}},
+	{flag: "fx-quote-ttl", env: "FX_QUOTE_TTL", usage: "validity of a locked quote", set: func(c *Config, v string) error {
+		return setDuration(&c.Features.FX.QuoteTTL, v)
+	}},
+	{flag: "hold-ttl", env: "HOLD_TTL", usage: "default validity of an authorization hold", set: func(c *Config, v string) error {
+		return setDuration(&c.Features.Holds.TTL, v)
+	}},
+	{flag: "hold-sweep-interval", env: "HOLD_SWEEP_INTERVAL", usage: "interval of expiring holds", set: func(c *Config, v string) error {
+		return setDuration(&c.Features.Holds.SweepInterval, v)
+	}},
+	{flag: "reconciliation-interval", env: "RECONCILIATION_INTERVAL", usage: "interval of balance checks, enables them", set: func(c *Config, v string) error {
+		c.Features.Reconciliation.Enabled = true
+		return setDuration(&c.Features.Reconciliation.Interval, v)
+	}},
+	{flag: "reconciliation-enabled", env: "RECONCILIATION_ENABLED", usage: "enable periodic balance checks", set: func(c *Config, v string) error {
+		return setBool(&c.Features.Reconciliation.Enabled, v)
+	}},
+	{flag: "max-amounts", env: "MAX_AMOUNTS", usage: "limits of a single money move by currency like USD=1000,EUR=900, no limits by default", set: func(c *Config, v string) error {
+		return setAmounts(&c.Limits.MaxAmounts, v)
+	}},
+	{flag: "log-redact-amounts", env: "LOG_REDACT_AMOUNTS", usage: "hide money amounts in logs", set: func(c *Config, v string) error {
+		return setBool(&c.Logging.RedactAmounts, v)
+	}},
+	{flag: "log-redact-keys", env: "LOG_REDACT_KEYS", usage: "hide idempotency keys in logs", set: func(c *Config, v string) error {
+		return setBool(&c.Logging.RedactKeys, v)
+	}},
+	{flag: "idempotency-window", env: "IDEMPOTENCY_WINDOW", usage: "time idempotency keys are kept", set: func(c *Config, v string) error {
+		return setDuration(&c.Limits.IdempotencyWindow, v)
+	}},
+	{flag: "idempotency-pending-timeout", env: "IDEMPOTENCY_PENDING_TIMEOUT", usage: "time a request may run before a retry with its idempotency key is served again", set: func(c *Config, v string) error {
+		return setDuration(&c.Limits.IdempotencyPendingTimeout, v)
+	}},
+	{flag: "idempotency-sweep-interval", env: "IDEMPOTENCY_SWEEP_INTERVAL", usage: "interval of deleting expired idempotency keys", set: func(c *Config, v string) error {
+		return setDuration(&c.Limits.IdempotencySweepInterval, v)
+	}},
+	{flag: "tracing-exporter", env: "TRACING_EXPORTER", usage: "where spans are written: none, stdout or file", set: func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{flag: "tracing-file", env: "TRACING_FILE", usage: "file spans are appended to by the file exporter", set: func(c *Config, v string) error {
+		c.Tracing.File = v
+		return nil
+	}},
+}
+
+// Load builds the config from defaults, the file named by -config or CONFIG_FILE, the environment and args.
+// It returns flag.ErrHelp when args ask for help.
+func Load(name string, args []string) (Config, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := flags.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.flag] = flags.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	c := Default()
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(&c, value); err != nil {
+				return Config{}, fmt.Errorf("parsing %s: %w", s.env, err)
+			}
+		}
+	}
+
+	passed := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+	for _, s := range settings {
+		if passed[s.flag] {
+			if err := s.set(&c, *values[s.flag]); err != nil {
+				return Config{}, fmt.Errorf("parsing -%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("decoding config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports all invalid settings together.
+func (c Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Port > 0 && c.HTTP.Port <= 65535, "http.port %d is not a valid port", c.HTTP.Port)
+	check(c.HTTP.ReadTimeout >= 0, "http.read_timeout is negative")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout is negative")
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout is negative")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle_timeout is negative")
+	check(c.HTTP.MaxBodyBytes > 0, "http.max_body_bytes is not positive")
+
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns is negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns is negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"db.max_idle_conns %d is more than db.max_open_conns %d", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime is negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time is negative")
+	check(!c.DB.AutoMigrate || c.Storage == StoragePostgres, "db.auto_migrate requires %s storage", StoragePostgres)
+
+	check(c.Storage == StoragePostgres || c.Storage == StorageMemory,
+		"storage %q is neither %s nor %s", c.Storage, StoragePostgres, StorageMemory)
+	check(c.ShutdownTimeout > 0, "shutdown_timeout is not positive")
+	check(c.DrainPeriod >= 0, "drain_period is negative")
+
+	fx := c.Features.FX
+	check(!fx.Enabled || fx.RatesFile != "", "features.fx.rates_file is required when fx is enabled")
+	check(fx.QuoteTTL > 0, "features.fx.quote_ttl is not positive")
+	check(c.Features.Holds.TTL > 0, "features.holds.ttl is not positive")
+	check(c.Features.Holds.SweepInterval > 0, "features.holds.sweep_interval is not positive")
+	check(c.Features.Reconciliation.Interval > 0, "features.reconciliation.interval is not positive")
+	check(!c.Features.Reconciliation.Enabled || c.Storage == StoragePostgres,
+		"features.reconciliation requires %s storage", StoragePostgres)
+
+	for currency, amount := range c.Limits.MaxAmounts {
+		code, err := money.ParseCurrency(string(currency))
+		check(err == nil && code == currency, "limits.max_amounts has unknown currency %q", currency)
+		check(amount.Sign() > 0, "limits.max_amounts.%s is not positive", currency)
+	}
+	check(c.Limits.IdempotencyWindow > 0, "limits.idempotency_window is not positive")
+	check(c.Limits.IdempotencyPendingTimeout > 0, "limits.idempotency_pending_timeout is not positive")
+	check(c.Limits.IdempotencySweepInterval > 0, "limits.idempotency_sweep_interval is not positive")
+
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingStdout:
+	case TracingFile:
+		check(c.Tracing.File != "", "tracing.file is required by the %s exporter", TracingFile)
+	default:
+		check(false, "tracing.exporter %q is none of %s, %s and %s", c.Tracing.Exporter, TracingNone, TracingStdout, TracingFile)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func setInt(dst *int, value string) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*dst = parsed
+	return nil
+}
+
+func setBool(dst *bool, value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*dst = parsed
+	return nil
+}
+
+func setDuration(dst *Duration, value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*dst = Duration(parsed)
+	return nil
+}
+
+// setAmounts parses a list like USD=1000,EUR=900.50 and replaces dst with it, an empty list removes the limits.
+func setAmounts(dst *map[money.Currency]money.Amount, value string) error {
+	amounts := make(map[money.Currency]money.Amount)
+	if value == "" {
+		*dst = amounts
+		return nil
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		pair := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(pair) != 2 {
+			return fmt.Errorf("%q is not a currency=amount pair", item)
+		}
+
+		currency, err := money.ParseCurrency(pair[0])
+		if err != nil {
+			return err
+		}
+		parsed, err := money.ParseAmount(pair[1])
+		if err != nil {
+			return err
+		}
+		amounts[currency] = parsed
+	}
+
+	*dst = amounts
+	return nil
+}
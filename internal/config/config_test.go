@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/money"
+)
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		_ = os.Unsetenv(key)
+	})
+}
+
+func TestDefault_IsValid(t *testing.T) {
+	require.NoError(t, Default().Validate())
+}
+
+func TestLoad_OverridesFileWithEnvAndEnvWithFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{
+		"http": {"port": 9000, "read_timeout": "3s"},
+		"db": {"max_open_conns": 50},
+		"shutdown_timeout": "20s",
+		"limits": {"max_amounts": {"USD": "1000.50"}}
+	}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	setenv(t, "HTTP_READ_TIMEOUT", "4s")
+	setenv(t, "SHUTDOWN_TIMEOUT", "30s")
+
+	c, err := Load("test", []string{"-config", path, "-shutdown-timeout", "40s"})
+	require.NoError(t, err)
+	require.Equal(t, 9000, c.HTTP.Port)
+	require.Equal(t, 4*time.Second, c.HTTP.ReadTimeout.Duration())
+	require.Equal(t, Default().HTTP.WriteTimeout, c.HTTP.WriteTimeout)
+	require.Equal(t, 50, c.DB.MaxOpenConns)
+	require.Equal(t, 40*time.Second, c.ShutdownTimeout.Duration())
+	require.Equal(t, map[money.Currency]money.Amount{"USD": money.NewAmount(100050, 2)}, c.Limits.MaxAmounts)
+}
+
+func TestLoad_EnablesFeaturesByTheirSettings(t *testing.T) {
+	setenv(t, "FX_RATES_FILE", "/app/fx-rates.json")
+	setenv(t, "RECONCILIATION_INTERVAL", "2h")
+
+	c, err := Load("test", nil)
+	require.NoError(t, err)
+	require.True(t, c.Features.FX.Enabled)
+	require.True(t, c.Features.Reconciliation.Enabled)
+	require.Equal(t, 2*time.Hour, c.Features.Reconciliation.Interval.Duration())
+
+	c, err = Load("test", []string{"-fx-enabled", "false"})
+	require.NoError(t, err)
+	require.False(t, c.Features.FX.Enabled)
+}
+
+func TestLoad_ReturnsErrorOnInvalidSetting(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "invalid duration",
+			args: []string{"-shutdown-timeout", "soon"},
+			want: "parsing -shutdown-timeout",
+		},
+		{
+			name: "invalid amount",
+			args: []string{"-max-amounts", "USD=lots"},
+			want: "parsing -max-amounts",
+		},
+		{
+			name: "amount without currency",
+			args: []string{"-max-amounts", "1000"},
+			want: `parsing -max-amounts: "1000" is not a currency=amount pair`,
+		},
+		{
+			name: "unknown limit currency",
+			args: []string{"-max-amounts", "USD=1000,XYZ=10"},
+			want: "unknown currency",
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-unknown", "1"},
+			want: "flag provided but not defined",
+		},
+		{
+			name: "missing file",
+			args: []string{"-config", filepath.Join(t.TempDir(), "missing.json")},
+			want: "opening config file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load("test", tt.args)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	c := Default()
+	c.HTTP.Port = 70000
+	c.DB.MaxIdleConns = 30
+	c.Storage = "sqlite"
+	c.Features.FX.Enabled = true
+	c.Tracing.Exporter = "jaeger"
+
+	err := c.Validate()
+	require.EqualError(t, err, "invalid config: http.port 70000 is not a valid port; "+
+		"db.max_idle_conns 30 is more than db.max_open_conns 20; "+
+		`storage "sqlite" is neither postgres nor memory; `+
+		"features.fx.rates_file is required when fx is enabled; "+
+		`tracing.exporter "jaeger" is none of none, stdout and file`)
+}
@@ -1,23 +1,39 @@
 package db
 
 import (
-	"log"
+	"database/sql"
+	"fmt"
 
 	"github.com/jackc/pgx"
 	"github.com/jackc/pgx/stdlib"
 	"github.com/jmoiron/sqlx"
+
+	"payment-system/internal/config"
+	"payment-system/internal/spanlog"
 )
 
-func New() *sqlx.DB {
-	config, err := pgx.ParseEnvLibpq()
+// New connects to the database configured by the libpq PG* environment variables.
+// Statements are traced when the context they run with carries a tracer.
+func New(pool config.DB) (*sqlx.DB, error) {
+	connConfig, err := pgx.ParseEnvLibpq()
 	if err != nil {
-		log.Fatalf("failed to init db: %s", err)
+		return nil, fmt.Errorf("parsing database environment: %w", err)
 	}
 
-	db := stdlib.OpenDB(config)
+	// the connection string only keeps the parsed TLS settings, without sslmode it would override them
+	driverConfig := &stdlib.DriverConfig{ConnConfig: connConfig}
+	stdlib.RegisterDriverConfig(driverConfig)
+
+	db := sql.OpenDB(spanlog.Connector(stdlib.GetDefaultDriver(), driverConfig.ConnectionString("sslmode=disable")))
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime.Duration())
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime.Duration())
+
 	if err := db.Ping(); err != nil {
-		log.Fatalf("failed to ping db: %s", err)
+		db.Close()
+		return nil, fmt.Errorf("pinging database: %w", err)
 	}
 
-	return sqlx.NewDb(db, "pgx")
+	return sqlx.NewDb(db, "pgx"), nil
 }
@@ -0,0 +1,84 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"payment-system/internal/money"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+type pair struct {
+	from money.Currency
+	to   money.Currency
+}
+
+type rateDTO struct {
+	From money.Currency `json:"from"`
+	To   money.Currency `json:"to"`
+	Rate money.Amount   `json:"rate"`
+}
+
+type ratesDTO struct {
+	Rates []rateDTO `json:"rates"`
+}
+
+// FileProvider serves exchange rates loaded from a JSON file:
+//
+//	{"rates": [{"from": "USD", "to": "EUR", "rate": "0.92"}]}
+//
+// Every direction has to be listed explicitly, rates are never inverted.
+type FileProvider struct {
+	rates map[pair]money.Amount
+}
+
+func LoadFile(path string) (*FileProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening rates file: %w", err)
+	}
+	defer file.Close()
+
+	var dto ratesDTO
+	if err := json.NewDecoder(file).Decode(&dto); err != nil {
+		return nil, fmt.Errorf("decoding rates file: %w", err)
+	}
+
+	rates := make(map[pair]money.Amount, len(dto.Rates))
+	for _, rate := range dto.Rates {
+		from, err := money.ParseCurrency(string(rate.From))
+		if err != nil {
+			return nil, fmt.Errorf("parsing rate source currency: %w", err)
+		}
+
+		to, err := money.ParseCurrency(string(rate.To))
+		if err != nil {
+			return nil, fmt.Errorf("parsing rate destination currency: %w", err)
+		}
+
+		if rate.Rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate %s to %s is not positive", from, to)
+		}
+
+		rates[pair{from: from, to: to}] = rate.Rate
+	}
+
+	return &FileProvider{rates: rates}, nil
+}
+
+func (p *FileProvider) Rate(_ context.Context, from, to money.Currency) (money.Amount, error) {
+	if from == to {
+		return money.NewAmount(1, 0), nil
+	}
+
+	rate, ok := p.rates[pair{from: from, to: to}]
+	if !ok {
+		return money.Amount{}, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+	}
+
+	return rate, nil
+}
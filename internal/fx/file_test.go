@@ -0,0 +1,56 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/money"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	content := `{"rates": [{"from": "usd", "to": "EUR", "rate": "0.92"}, {"from": "USD", "to": "JPY", "rate": 145.12}]}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	provider, err := LoadFile(path)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, money.NewAmount(92, 2), rate)
+
+	rate, err = provider.Rate(context.Background(), "USD", "JPY")
+	require.NoError(t, err)
+	require.Equal(t, money.NewAmount(14512, 2), rate)
+
+	rate, err = provider.Rate(context.Background(), "EUR", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, money.NewAmount(1, 0), rate)
+
+	_, err = provider.Rate(context.Background(), "EUR", "USD")
+	require.True(t, errors.Is(err, ErrRateNotFound))
+}
+
+func TestLoadFile_ReturnsErrorOnInvalidRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown currency", content: `{"rates": [{"from": "USD", "to": "ABC", "rate": "1"}]}`},
+		{name: "zero rate", content: `{"rates": [{"from": "USD", "to": "EUR", "rate": "0"}]}`},
+		{name: "invalid json", content: `{"rates": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rates.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			_, err := LoadFile(path)
+			require.Error(t, err)
+		})
+	}
+}
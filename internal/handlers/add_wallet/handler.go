@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
+	"payment-system/internal/handlers/httperror"
 	"payment-system/internal/wallet"
 )
 
@@ -25,22 +25,18 @@ func NewHandler(walletService walletService) *Handler {
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	dto, err := validate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err = fmt.Errorf("failed to validate request: %w", err)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write bad request error message: %s\n", err)
-		}
+		httperror.WriteBadRequest(w, fmt.Errorf("failed to validate request: %w", err))
 		return
 	}
 
 	ctx := r.Context()
-	info := wallet.Wallet{IdempotencyKey: dto.IdempotencyKey}
+	info := wallet.Wallet{
+		IdempotencyKey: dto.IdempotencyKey,
+		Currency:       dto.Currency,
+	}
 	walletID, err := h.walletService.AddWallet(ctx, info)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write error message: %s\n", err)
-		}
+		httperror.WriteServiceError(r.Context(), w, err)
 		return
 	}
 
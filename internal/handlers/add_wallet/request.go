@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"payment-system/internal/money"
 )
 
 type WalletInDTO struct {
-	IdempotencyKey string `json:"idempotency_key"`
+	IdempotencyKey string         `json:"idempotency_key"`
+	Currency       money.Currency `json:"currency"`
 }
 
 func validate(r *http.Request) (WalletInDTO, error) {
@@ -21,5 +24,15 @@ func validate(r *http.Request) (WalletInDTO, error) {
 		return WalletInDTO{}, fmt.Errorf("idempotency_key is empty")
 	}
 
+	if wallet.Currency == "" {
+		wallet.Currency = money.USD
+	}
+
+	currency, err := money.ParseCurrency(string(wallet.Currency))
+	if err != nil {
+		return WalletInDTO{}, fmt.Errorf("currency is invalid: %w", err)
+	}
+	wallet.Currency = currency
+
 	return wallet, nil
 }
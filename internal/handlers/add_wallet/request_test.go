@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"payment-system/internal/money"
 )
 
 func Test_validate(t *testing.T) {
@@ -35,12 +37,32 @@ func Test_validate(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "no err",
+			name: "err on unknown currency",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"currency\": \"ABC\"}")),
+			},
+			want:    WalletInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err with default currency",
 			args: args{
 				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\"}")),
 			},
 			want: WalletInDTO{
 				IdempotencyKey: "foo",
+				Currency:       money.USD,
+			},
+			wantErr: false,
+		},
+		{
+			name: "no err",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"currency\": \"jpy\"}")),
+			},
+			want: WalletInDTO{
+				IdempotencyKey: "foo",
+				Currency:       "JPY",
 			},
 			wantErr: false,
 		},
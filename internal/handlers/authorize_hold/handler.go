@@ -0,0 +1,63 @@
+package authorize_hold
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+// initiatorHeader optionally names the caller, it is recorded on the created transaction.
+const initiatorHeader = "X-Initiator"
+
+type walletService interface {
+	Authorize(ctx context.Context, authorization wallet.Authorization) (wallet.Hold, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	authorization := wallet.Authorization{
+		FromWalletID:   dto.FromWalletID,
+		ToWalletID:     dto.ToWalletID,
+		Value:          dto.Value,
+		Currency:       dto.Currency,
+		Initiator:      r.Header.Get(initiatorHeader),
+		IdempotencyKey: dto.IdempotencyKey,
+	}
+	hold, err := h.walletService.Authorize(ctx, authorization)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := HoldOutDTO{
+		HoldID:        hold.ID,
+		TransactionID: hold.TransactionID,
+		FromWalletID:  hold.FromWalletID,
+		ToWalletID:    hold.ToWalletID,
+		Value:         hold.Value,
+		CapturedValue: hold.CapturedValue,
+		Currency:      hold.Currency,
+		Status:        hold.Status,
+		ExpiresAt:     hold.ExpiresAt,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,51 @@
+package authorize_hold
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/money"
+)
+
+type AuthorizationDTO struct {
+	IdempotencyKey string         `json:"idempotency_key"`
+	FromWalletID   int64          `json:"from_wallet_id"`
+	ToWalletID     int64          `json:"to_wallet_id"`
+	Value          money.Amount   `json:"value"`
+	Currency       money.Currency `json:"currency,omitempty"`
+}
+
+func validate(r *http.Request) (AuthorizationDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var authorization AuthorizationDTO
+	if err := decoder.Decode(&authorization); err != nil {
+		return AuthorizationDTO{}, err
+	}
+
+	if authorization.IdempotencyKey == "" {
+		return AuthorizationDTO{}, fmt.Errorf("idempotency_key is empty")
+	}
+
+	if authorization.FromWalletID == 0 {
+		return AuthorizationDTO{}, fmt.Errorf("from_wallet_id is empty")
+	}
+
+	if authorization.ToWalletID == 0 {
+		return AuthorizationDTO{}, fmt.Errorf("to_wallet_id is empty")
+	}
+
+	if authorization.Value.IsZero() {
+		return AuthorizationDTO{}, fmt.Errorf("value is empty")
+	}
+
+	if authorization.Currency != "" {
+		currency, err := money.ParseCurrency(string(authorization.Currency))
+		if err != nil {
+			return AuthorizationDTO{}, fmt.Errorf("currency is invalid: %w", err)
+		}
+		authorization.Currency = currency
+	}
+
+	return authorization, nil
+}
@@ -0,0 +1,82 @@
+package authorize_hold
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"payment-system/internal/money"
+)
+
+func Test_validate(t *testing.T) {
+	type args struct {
+		r *http.Request
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    AuthorizationDTO
+		wantErr bool
+	}{
+		{
+			name: "err on empty request",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("")),
+			},
+			want:    AuthorizationDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty idempotency_key",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{}")),
+			},
+			want:    AuthorizationDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty to_wallet_id",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"from_wallet_id\": 1}")),
+			},
+			want:    AuthorizationDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty value",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"from_wallet_id\": 1, \"to_wallet_id\": 2}")),
+			},
+			want:    AuthorizationDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"from_wallet_id\": 1, \"to_wallet_id\": 2, \"value\": \"10.50\", \"currency\": \"usd\"}")),
+			},
+			want: AuthorizationDTO{
+				IdempotencyKey: "foo",
+				FromWalletID:   1,
+				ToWalletID:     2,
+				Value:          money.NewAmount(1050, 2),
+				Currency:       money.USD,
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validate(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package authorize_hold
+
+import (
+	"time"
+
+	"payment-system/internal/money"
+)
+
+type HoldOutDTO struct {
+	HoldID        int64          `json:"hold_id"`
+	TransactionID int64          `json:"transaction_id"`
+	FromWalletID  int64          `json:"from_wallet_id"`
+	ToWalletID    int64          `json:"to_wallet_id"`
+	Value         money.Amount   `json:"value"`
+	CapturedValue money.Amount   `json:"captured_value"`
+	Currency      money.Currency `json:"currency"`
+	Status        string         `json:"status"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+}
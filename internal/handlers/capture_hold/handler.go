@@ -0,0 +1,56 @@
+package capture_hold
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+type walletService interface {
+	Capture(ctx context.Context, capture wallet.Capture) (wallet.Hold, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	capture := wallet.Capture{
+		HoldID: dto.HoldID,
+		Value:  dto.Value,
+	}
+	hold, err := h.walletService.Capture(ctx, capture)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := HoldOutDTO{
+		HoldID:        hold.ID,
+		TransactionID: hold.TransactionID,
+		FromWalletID:  hold.FromWalletID,
+		ToWalletID:    hold.ToWalletID,
+		Value:         hold.Value,
+		CapturedValue: hold.CapturedValue,
+		Currency:      hold.Currency,
+		Status:        hold.Status,
+		ExpiresAt:     hold.ExpiresAt,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
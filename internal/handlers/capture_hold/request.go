@@ -0,0 +1,30 @@
+package capture_hold
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/money"
+)
+
+// CaptureDTO captures the whole hold when value is empty.
+type CaptureDTO struct {
+	IdempotencyKey string       `json:"idempotency_key,omitempty"`
+	HoldID         int64        `json:"hold_id"`
+	Value          money.Amount `json:"value"`
+}
+
+func validate(r *http.Request) (CaptureDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var capture CaptureDTO
+	if err := decoder.Decode(&capture); err != nil {
+		return CaptureDTO{}, err
+	}
+
+	if capture.HoldID == 0 {
+		return CaptureDTO{}, fmt.Errorf("hold_id is empty")
+	}
+
+	return capture, nil
+}
@@ -0,0 +1,73 @@
+package capture_hold
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"payment-system/internal/money"
+)
+
+func Test_validate(t *testing.T) {
+	type args struct {
+		r *http.Request
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    CaptureDTO
+		wantErr bool
+	}{
+		{
+			name: "err on empty request",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("")),
+			},
+			want:    CaptureDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty hold_id",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{}")),
+			},
+			want:    CaptureDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err on whole hold",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"hold_id\": 1}")),
+			},
+			want: CaptureDTO{
+				HoldID: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "no err on partial capture",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"hold_id\": 1, \"value\": \"5.25\"}")),
+			},
+			want: CaptureDTO{
+				HoldID: 1,
+				Value:  money.NewAmount(525, 2),
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validate(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,14 +2,18 @@ package deposit_money
 
 import (
 	"context"
-	"log"
+	"encoding/json"
 	"net/http"
 
+	"payment-system/internal/handlers/httperror"
 	"payment-system/internal/wallet"
 )
 
+// initiatorHeader optionally names the caller, it is recorded on the created transaction.
+const initiatorHeader = "X-Initiator"
+
 type walletService interface {
-	DepositMoney(ctx context.Context, deposit wallet.Deposit) error
+	DepositMoney(ctx context.Context, deposit wallet.Deposit) (int64, error)
 }
 
 type Handler struct {
@@ -23,10 +27,7 @@ func NewHandler(walletService walletService) *Handler {
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	dto, err := validate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write bad request error message: %s\n", err)
-		}
+		httperror.WriteBadRequest(w, err)
 		return
 	}
 
@@ -34,12 +35,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	deposit := wallet.Deposit{
 		WalletID:       dto.WalletID,
 		Value:          dto.Value,
+		Currency:       dto.Currency,
+		Initiator:      r.Header.Get(initiatorHeader),
 		IdempotencyKey: dto.IdempotencyKey,
 	}
-	if err := h.walletService.DepositMoney(ctx, deposit); err != nil {
+	transactionID, err := h.walletService.DepositMoney(ctx, deposit)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := DepositOutDTO{TransactionID: transactionID}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write error message: %s\n", err)
-		}
 	}
 }
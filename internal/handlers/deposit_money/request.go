@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"payment-system/internal/money"
 )
 
 type DepositDTO struct {
-	IdempotencyKey string  `json:"idempotency_key"`
-	WalletID       int64   `json:"wallet_id"`
-	Value          float64 `json:"value"`
+	IdempotencyKey string         `json:"idempotency_key"`
+	WalletID       int64          `json:"wallet_id"`
+	Value          money.Amount   `json:"value"`
+	Currency       money.Currency `json:"currency,omitempty"`
 }
 
 func validate(r *http.Request) (DepositDTO, error) {
@@ -27,9 +30,17 @@ func validate(r *http.Request) (DepositDTO, error) {
 		return DepositDTO{}, fmt.Errorf("wallet_id is empty")
 	}
 
-	if deposit.Value == 0 {
+	if deposit.Value.IsZero() {
 		return DepositDTO{}, fmt.Errorf("deposit_value is empty")
 	}
 
+	if deposit.Currency != "" {
+		currency, err := money.ParseCurrency(string(deposit.Currency))
+		if err != nil {
+			return DepositDTO{}, fmt.Errorf("currency is invalid: %w", err)
+		}
+		deposit.Currency = currency
+	}
+
 	return deposit, nil
 }
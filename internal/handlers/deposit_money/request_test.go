@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"payment-system/internal/money"
 )
 
 func Test_validate(t *testing.T) {
@@ -50,6 +52,34 @@ func Test_validate(t *testing.T) {
 			want:    DepositDTO{},
 			wantErr: true,
 		},
+		{
+			name: "err on invalid value",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1, \"value\": \"1,5\"}")),
+			},
+			want:    DepositDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on unknown currency",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1, \"value\": 1, \"currency\": \"ABC\"}")),
+			},
+			want:    DepositDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err on string value",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1, \"value\": \"0.29\"}")),
+			},
+			want: DepositDTO{
+				IdempotencyKey: "foo",
+				WalletID:       1,
+				Value:          money.NewAmount(29, 2),
+			},
+			wantErr: false,
+		},
 		{
 			name: "no err",
 			args: args{
@@ -58,7 +88,7 @@ func Test_validate(t *testing.T) {
 			want: DepositDTO{
 				IdempotencyKey: "foo",
 				WalletID:       1,
-				Value:          100.55,
+				Value:          money.NewAmount(10055, 2),
 			},
 			wantErr: false,
 		},
@@ -0,0 +1,5 @@
+package deposit_money
+
+type DepositOutDTO struct {
+	TransactionID int64 `json:"transaction_id"`
+}
@@ -0,0 +1,49 @@
+package get_balance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+type walletService interface {
+	GetBalance(ctx context.Context, walletID int64) (wallet.Balance, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, fmt.Errorf("failed to validate request: %w", err))
+		return
+	}
+
+	ctx := r.Context()
+	balance, err := h.walletService.GetBalance(ctx, dto.WalletID)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := BalanceOutDTO{
+		WalletID:         balance.WalletID,
+		Balance:          balance.Value,
+		AvailableBalance: balance.Available,
+		Currency:         balance.Currency,
+		UpdatedAt:        balance.UpdatedAt,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
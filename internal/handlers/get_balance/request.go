@@ -0,0 +1,25 @@
+package get_balance
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type BalanceInDTO struct {
+	WalletID int64 `json:"wallet_id"`
+}
+
+func validate(r *http.Request) (BalanceInDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var balance BalanceInDTO
+	if err := decoder.Decode(&balance); err != nil {
+		return BalanceInDTO{}, err
+	}
+
+	if balance.WalletID == 0 {
+		return BalanceInDTO{}, fmt.Errorf("wallet_id is empty")
+	}
+
+	return balance, nil
+}
@@ -0,0 +1,60 @@
+package get_balance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_validate(t *testing.T) {
+	type args struct {
+		r *http.Request
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    BalanceInDTO
+		wantErr bool
+	}{
+		{
+			name: "err on empty request",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("")),
+			},
+			want:    BalanceInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty wallet_id",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{}")),
+			},
+			want:    BalanceInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1}")),
+			},
+			want: BalanceInDTO{
+				WalletID: 1,
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validate(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
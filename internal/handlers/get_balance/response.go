@@ -0,0 +1,15 @@
+package get_balance
+
+import (
+	"time"
+
+	"payment-system/internal/money"
+)
+
+type BalanceOutDTO struct {
+	WalletID         int64          `json:"wallet_id"`
+	Balance          money.Amount   `json:"balance"`
+	AvailableBalance money.Amount   `json:"available_balance"`
+	Currency         money.Currency `json:"currency"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
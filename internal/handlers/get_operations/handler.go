@@ -3,16 +3,22 @@ package get_operations
 import (
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/logging"
 	"payment-system/internal/wallet"
 )
 
+// NextCursorHeader holds the cursor of the next page, it is absent on the last page.
+const NextCursorHeader = "X-Next-Cursor"
+
 type walletService interface {
-	GetOperations(ctx context.Context, filter wallet.Filter) ([]wallet.Operation, error)
+	GetOperations(ctx context.Context, filter wallet.Filter) (wallet.OperationsPage, error)
 }
 
 type Handler struct {
@@ -26,43 +32,88 @@ func NewHandler(walletService walletService) *Handler {
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	dto, err := validate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err = fmt.Errorf("failed to validate request: %w", err)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write bad request error message: %s\n", err)
-		}
+		httperror.WriteBadRequest(w, fmt.Errorf("failed to validate request: %w", err))
 		return
 	}
 
 	ctx := r.Context()
 	filter := wallet.Filter{
 		WalletID:  dto.WalletID,
-		Date:      dto.Date,
+		From:      dto.From,
+		To:        dto.To,
 		Direction: dto.Direction,
+		Limit:     dto.Limit,
+		Cursor:    dto.Cursor,
 	}
-	operations, err := h.walletService.GetOperations(ctx, filter)
+	page, err := h.walletService.GetOperations(ctx, filter)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write error message: %s\n", err)
-		}
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	if page.NextCursor != "" {
+		w.Header().Set(NextCursorHeader, page.NextCursor)
+	}
+
+	if dto.Format == FormatJSON {
+		writeJSON(ctx, w, page)
 		return
 	}
 
-	records := make([][]string, 0, len(operations)+1)
-	records = append(records, []string{"wallet_id", "value", "direction", "date"})
-	for _, operation := range operations {
+	writeCSV(ctx, w, page)
+}
+
+func writeCSV(ctx context.Context, w http.ResponseWriter, page wallet.OperationsPage) {
+	records := make([][]string, 0, len(page.Operations)+1)
+	records = append(records, []string{"wallet_id", "value", "direction", "date", "id", "created_at",
+		"transaction_id", "counterparty_wallet_id", "original_transaction_id"})
+	for _, operation := range page.Operations {
 		walletID := strconv.FormatInt(operation.WalletID, 10)
-		value := strconv.FormatFloat(operation.Value, 'f', 2, 64)
+		value := operation.Value.String()
 		direction := strconv.Itoa(int(operation.Direction))
-		record := []string{walletID, value, direction, operation.Date}
+		id := strconv.FormatInt(operation.ID, 10)
+		createdAt := operation.CreatedAt.Format(time.RFC3339Nano)
+		transactionID := strconv.FormatInt(operation.TransactionID, 10)
+		counterpartyWalletID := ""
+		if operation.CounterpartyWalletID != 0 {
+			counterpartyWalletID = strconv.FormatInt(operation.CounterpartyWalletID, 10)
+		}
+		originalTransactionID := ""
+		if operation.OriginalTransactionID != 0 {
+			originalTransactionID = strconv.FormatInt(operation.OriginalTransactionID, 10)
+		}
+		record := []string{walletID, value, direction, operation.Date, id, createdAt, transactionID, counterpartyWalletID,
+			originalTransactionID}
 		records = append(records, record)
 	}
 
-	if err = csv.NewWriter(w).WriteAll(records); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write error message: %s\n", err)
-		}
+	w.Header().Set("Content-Type", "text/csv")
+	if err := csv.NewWriter(w).WriteAll(records); err != nil {
+		logging.FromContext(ctx).Error("failed to write operations", "error", err)
+	}
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, page wallet.OperationsPage) {
+	out := OperationsOutDTO{
+		Operations: make([]OperationOutDTO, 0, len(page.Operations)),
+		NextCursor: page.NextCursor,
+	}
+	for _, operation := range page.Operations {
+		out.Operations = append(out.Operations, OperationOutDTO{
+			ID:                    operation.ID,
+			TransactionID:         operation.TransactionID,
+			OriginalTransactionID: operation.OriginalTransactionID,
+			WalletID:              operation.WalletID,
+			CounterpartyWalletID:  operation.CounterpartyWalletID,
+			Value:                 operation.Value,
+			Direction:             operation.Direction,
+			Date:                  operation.Date,
+			CreatedAt:             operation.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logging.FromContext(ctx).Error("failed to write operations", "error", err)
 	}
 }
@@ -7,10 +7,26 @@ import (
 	"time"
 )
 
+const (
+	dateLayout         = "2006-01-02"
+	maxOperationsLimit = 1000
+
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// FilterDTO selects operations between from and to dates inclusive, both bounds are optional.
+// Date is a shortcut for the same from and to. Direction is optional, both directions are selected without it.
+// Format is csv by default.
 type FilterDTO struct {
 	WalletID  int64  `json:"wallet_id"`
-	Date      string `json:"date"`
-	Direction int8   `json:"direction"`
+	Date      string `json:"date,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Direction *int8  `json:"direction,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+	Cursor    string `json:"cursor,omitempty"`
+	Format    string `json:"format,omitempty"`
 }
 
 func validate(r *http.Request) (FilterDTO, error) {
@@ -24,12 +40,45 @@ func validate(r *http.Request) (FilterDTO, error) {
 		return FilterDTO{}, fmt.Errorf("wallet_id is empty")
 	}
 
-	if filter.Date == "" {
-		return FilterDTO{}, fmt.Errorf("date is empty")
+	if filter.Date != "" {
+		if filter.From != "" || filter.To != "" {
+			return FilterDTO{}, fmt.Errorf("date can not be used with from and to")
+		}
+		filter.From, filter.To, filter.Date = filter.Date, filter.Date, ""
+	}
+
+	var from, to time.Time
+	var err error
+	if filter.From != "" {
+		if from, err = time.Parse(dateLayout, filter.From); err != nil {
+			return FilterDTO{}, fmt.Errorf("from is invalid: %w", err)
+		}
+	}
+
+	if filter.To != "" {
+		if to, err = time.Parse(dateLayout, filter.To); err != nil {
+			return FilterDTO{}, fmt.Errorf("to is invalid: %w", err)
+		}
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return FilterDTO{}, fmt.Errorf("to is before from")
+	}
+
+	if filter.Direction != nil && *filter.Direction != 0 && *filter.Direction != 1 {
+		return FilterDTO{}, fmt.Errorf("direction is invalid: %d", *filter.Direction)
+	}
+
+	if filter.Limit < 0 || filter.Limit > maxOperationsLimit {
+		return FilterDTO{}, fmt.Errorf("limit is not in range from 1 to %d", maxOperationsLimit)
 	}
 
-	if _, err := time.Parse("2006-01-02", filter.Date); err != nil {
-		return FilterDTO{}, fmt.Errorf("date is invalid: %w", err)
+	switch filter.Format {
+	case "":
+		filter.Format = FormatCSV
+	case FormatCSV, FormatJSON:
+	default:
+		return FilterDTO{}, fmt.Errorf("format is not one of %s, %s: %q", FormatCSV, FormatJSON, filter.Format)
 	}
 
 	return filter, nil
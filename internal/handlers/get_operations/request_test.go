@@ -12,6 +12,7 @@ func Test_validate(t *testing.T) {
 	type args struct {
 		r *http.Request
 	}
+	outcome := int8(1)
 	tests := []struct {
 		name    string
 		args    args
@@ -35,21 +36,87 @@ func Test_validate(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "err on empty date",
+			name: "err on invalid date",
 			args: args{
-				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1}")),
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"date\": \"boo\"}")),
 			},
 			want:    FilterDTO{},
 			wantErr: true,
 		},
 		{
-			name: "err on invalid date",
+			name: "err on date with range",
 			args: args{
-				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"date\": \"boo\"}")),
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"date\": \"2021-06-30\", \"from\": \"2021-06-01\"}")),
+			},
+			want:    FilterDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on invalid from",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"from\": \"boo\"}")),
+			},
+			want:    FilterDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on to before from",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"from\": \"2021-06-30\", \"to\": \"2021-06-01\"}")),
+			},
+			want:    FilterDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on invalid direction",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"direction\": 2}")),
+			},
+			want:    FilterDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on too big limit",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"limit\": 1001}")),
 			},
 			want:    FilterDTO{},
 			wantErr: true,
 		},
+		{
+			name: "err on unknown format",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"format\": \"xml\"}")),
+			},
+			want:    FilterDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err without filters",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1}")),
+			},
+			want: FilterDTO{
+				WalletID: 1,
+				Format:   FormatCSV,
+			},
+			wantErr: false,
+		},
+		{
+			name: "no err with range",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"wallet_id\": 1, \"from\": \"2021-06-01\", \"to\": \"2021-06-30\", \"limit\": 10, \"cursor\": \"boo\", \"format\": \"json\"}")),
+			},
+			want: FilterDTO{
+				WalletID: 1,
+				From:     "2021-06-01",
+				To:       "2021-06-30",
+				Limit:    10,
+				Cursor:   "boo",
+				Format:   FormatJSON,
+			},
+			wantErr: false,
+		},
 		{
 			name: "no err",
 			args: args{
@@ -57,8 +124,10 @@ func Test_validate(t *testing.T) {
 			},
 			want: FilterDTO{
 				WalletID:  1,
-				Date:      "2021-06-30",
-				Direction: 1,
+				From:      "2021-06-30",
+				To:        "2021-06-30",
+				Direction: &outcome,
+				Format:    FormatCSV,
 			},
 			wantErr: false,
 		},
@@ -0,0 +1,25 @@
+package get_operations
+
+import (
+	"time"
+
+	"payment-system/internal/money"
+)
+
+type OperationOutDTO struct {
+	ID            int64 `json:"id"`
+	TransactionID int64 `json:"transaction_id"`
+	// OriginalTransactionID is the refunded transaction of a refund operation.
+	OriginalTransactionID int64        `json:"original_transaction_id,omitempty"`
+	WalletID              int64        `json:"wallet_id"`
+	CounterpartyWalletID  int64        `json:"counterparty_wallet_id,omitempty"`
+	Value                 money.Amount `json:"value"`
+	Direction             int8         `json:"direction"`
+	Date                  string       `json:"date"`
+	CreatedAt             time.Time    `json:"created_at"`
+}
+
+type OperationsOutDTO struct {
+	Operations []OperationOutDTO `json:"operations"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
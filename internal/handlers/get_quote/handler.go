@@ -0,0 +1,50 @@
+package get_quote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/money"
+	"payment-system/internal/wallet"
+)
+
+type walletService interface {
+	LockQuote(ctx context.Context, from, to money.Currency) (wallet.Quote, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, fmt.Errorf("failed to validate request: %w", err))
+		return
+	}
+
+	ctx := r.Context()
+	quote, err := h.walletService.LockQuote(ctx, dto.FromCurrency, dto.ToCurrency)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := QuoteOutDTO{
+		QuoteID:      quote.ID,
+		FromCurrency: quote.From,
+		ToCurrency:   quote.To,
+		Rate:         quote.Rate,
+		ExpiresAt:    quote.ExpiresAt,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
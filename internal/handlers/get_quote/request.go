@@ -0,0 +1,38 @@
+package get_quote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/money"
+)
+
+type QuoteInDTO struct {
+	FromCurrency money.Currency `json:"from_currency"`
+	ToCurrency   money.Currency `json:"to_currency"`
+}
+
+func validate(r *http.Request) (QuoteInDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var quote QuoteInDTO
+	if err := decoder.Decode(&quote); err != nil {
+		return QuoteInDTO{}, err
+	}
+
+	from, err := money.ParseCurrency(string(quote.FromCurrency))
+	if err != nil {
+		return QuoteInDTO{}, fmt.Errorf("from_currency is invalid: %w", err)
+	}
+
+	to, err := money.ParseCurrency(string(quote.ToCurrency))
+	if err != nil {
+		return QuoteInDTO{}, fmt.Errorf("to_currency is invalid: %w", err)
+	}
+
+	if from == to {
+		return QuoteInDTO{}, fmt.Errorf("from_currency and to_currency are the same")
+	}
+
+	return QuoteInDTO{FromCurrency: from, ToCurrency: to}, nil
+}
@@ -0,0 +1,77 @@
+package get_quote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_validate(t *testing.T) {
+	type args struct {
+		r *http.Request
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    QuoteInDTO
+		wantErr bool
+	}{
+		{
+			name: "err on empty request",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("")),
+			},
+			want:    QuoteInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty from_currency",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"to_currency\": \"EUR\"}")),
+			},
+			want:    QuoteInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on unknown to_currency",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"from_currency\": \"USD\", \"to_currency\": \"ABC\"}")),
+			},
+			want:    QuoteInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on same currencies",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"from_currency\": \"USD\", \"to_currency\": \"usd\"}")),
+			},
+			want:    QuoteInDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"from_currency\": \"usd\", \"to_currency\": \"EUR\"}")),
+			},
+			want: QuoteInDTO{
+				FromCurrency: "USD",
+				ToCurrency:   "EUR",
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validate(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
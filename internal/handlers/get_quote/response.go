@@ -0,0 +1,15 @@
+package get_quote
+
+import (
+	"time"
+
+	"payment-system/internal/money"
+)
+
+type QuoteOutDTO struct {
+	QuoteID      string         `json:"quote_id"`
+	FromCurrency money.Currency `json:"from_currency"`
+	ToCurrency   money.Currency `json:"to_currency"`
+	Rate         money.Amount   `json:"rate"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+}
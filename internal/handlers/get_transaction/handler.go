@@ -0,0 +1,63 @@
+package get_transaction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+type walletService interface {
+	GetTransaction(ctx context.Context, transactionID int64) (wallet.Transaction, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, fmt.Errorf("failed to validate request: %w", err))
+		return
+	}
+
+	ctx := r.Context()
+	transaction, err := h.walletService.GetTransaction(ctx, dto.TransactionID)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := TransactionOutDTO{
+		TransactionID:         transaction.ID,
+		Type:                  transaction.Type,
+		Status:                transaction.Status,
+		Initiator:             transaction.Initiator,
+		OriginalTransactionID: transaction.OriginalTransactionID,
+		RefundedValue:         transaction.RefundedValue,
+		CreatedAt:             transaction.CreatedAt,
+		Legs:                  make([]LegOutDTO, 0, len(transaction.Legs)),
+	}
+	for _, leg := range transaction.Legs {
+		response.Legs = append(response.Legs, LegOutDTO{
+			OperationID:          leg.OperationID,
+			WalletID:             leg.WalletID,
+			CounterpartyWalletID: leg.CounterpartyWalletID,
+			Value:                leg.Value,
+			Currency:             leg.Currency,
+			Direction:            leg.Direction,
+			CreatedAt:            leg.CreatedAt,
+		})
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
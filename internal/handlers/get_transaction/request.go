@@ -0,0 +1,25 @@
+package get_transaction
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type TransactionInDTO struct {
+	TransactionID int64 `json:"transaction_id"`
+}
+
+func validate(r *http.Request) (TransactionInDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var transaction TransactionInDTO
+	if err := decoder.Decode(&transaction); err != nil {
+		return TransactionInDTO{}, err
+	}
+
+	if transaction.TransactionID == 0 {
+		return TransactionInDTO{}, fmt.Errorf("transaction_id is empty")
+	}
+
+	return transaction, nil
+}
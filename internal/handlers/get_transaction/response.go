@@ -0,0 +1,29 @@
+package get_transaction
+
+import (
+	"time"
+
+	"payment-system/internal/money"
+)
+
+type TransactionOutDTO struct {
+	TransactionID int64  `json:"transaction_id"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	Initiator     string `json:"initiator"`
+	// OriginalTransactionID is set for refunds, RefundedValue is in the currency of the destination wallet.
+	OriginalTransactionID int64        `json:"original_transaction_id,omitempty"`
+	RefundedValue         money.Amount `json:"refunded_value"`
+	CreatedAt             time.Time    `json:"created_at"`
+	Legs                  []LegOutDTO  `json:"legs"`
+}
+
+type LegOutDTO struct {
+	OperationID          int64          `json:"operation_id"`
+	WalletID             int64          `json:"wallet_id"`
+	CounterpartyWalletID int64          `json:"counterparty_wallet_id,omitempty"`
+	Value                money.Amount   `json:"value"`
+	Currency             money.Currency `json:"currency"`
+	Direction            int8           `json:"direction"`
+	CreatedAt            time.Time      `json:"created_at"`
+}
@@ -0,0 +1,152 @@
+package httperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"payment-system/internal/logging"
+	"payment-system/internal/wallet"
+)
+
+const (
+	CodeInvalidRequest           = "invalid_request"
+	CodeRequestTooLarge          = "request_too_large"
+	CodeInvalidAmount            = "invalid_amount"
+	CodeValidationFailed         = "validation_failed"
+	CodeSameWallet               = "same_wallet"
+	CodeAmountLimitExceeded      = "amount_limit_exceeded"
+	CodeCurrencyMismatch         = "currency_mismatch"
+	CodeConversionUnsupported    = "conversion_unsupported"
+	CodeInvalidCursor            = "invalid_cursor"
+	CodeQuoteNotFound            = "quote_not_found"
+	CodeQuoteExpired             = "quote_expired"
+	CodeQuoteUsed                = "quote_used"
+	CodeDuplicate                = "duplicate"
+	CodeWalletNotFound           = "wallet_not_found"
+	CodeTransactionNotFound      = "transaction_not_found"
+	CodeHoldNotFound             = "hold_not_found"
+	CodeHoldNotAuthorized        = "hold_not_authorized"
+	CodeCaptureExceedsHold       = "capture_exceeds_hold"
+	CodeNotRefundable            = "not_refundable"
+	CodeRefundExceedsTransaction = "refund_exceeds_transaction"
+	CodeInsufficientFunds        = "insufficient_funds"
+	CodeConcurrentUpdate         = "concurrent_update"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeRequestInProgress        = "request_in_progress"
+	CodeShuttingDown             = "shutting_down"
+	CodeNotFound                 = "not_found"
+	CodeMethodNotAllowed         = "method_not_allowed"
+	CodeInternal                 = "internal_error"
+)
+
+type ErrorDTO struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Violations lists every invalid field of a request which failed validation in the wallet service.
+	Violations []ViolationDTO `json:"violations,omitempty"`
+}
+
+type ViolationDTO struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+var mappings = []mapping{
+	{err: wallet.ErrInvalidAmount, status: http.StatusBadRequest, code: CodeInvalidAmount},
+	{err: wallet.ErrSameWallet, status: http.StatusUnprocessableEntity, code: CodeSameWallet},
+	{err: wallet.ErrAmountLimitExceeded, status: http.StatusUnprocessableEntity, code: CodeAmountLimitExceeded},
+	{err: wallet.ErrCurrencyMismatch, status: http.StatusUnprocessableEntity, code: CodeCurrencyMismatch},
+	{err: wallet.ErrConversionUnsupported, status: http.StatusUnprocessableEntity, code: CodeConversionUnsupported},
+	{err: wallet.ErrInvalidCursor, status: http.StatusBadRequest, code: CodeInvalidCursor},
+	{err: wallet.ErrQuoteNotFound, status: http.StatusNotFound, code: CodeQuoteNotFound},
+	{err: wallet.ErrQuoteExpired, status: http.StatusUnprocessableEntity, code: CodeQuoteExpired},
+	{err: wallet.ErrQuoteUsed, status: http.StatusConflict, code: CodeQuoteUsed},
+	{err: wallet.ErrDuplicate, status: http.StatusConflict, code: CodeDuplicate},
+	{err: wallet.ErrWalletNotFound, status: http.StatusNotFound, code: CodeWalletNotFound},
+	{err: wallet.ErrTransactionNotFound, status: http.StatusNotFound, code: CodeTransactionNotFound},
+	{err: wallet.ErrHoldNotFound, status: http.StatusNotFound, code: CodeHoldNotFound},
+	{err: wallet.ErrHoldNotAuthorized, status: http.StatusConflict, code: CodeHoldNotAuthorized},
+	{err: wallet.ErrCaptureExceedsHold, status: http.StatusUnprocessableEntity, code: CodeCaptureExceedsHold},
+	{err: wallet.ErrNotRefundable, status: http.StatusConflict, code: CodeNotRefundable},
+	{err: wallet.ErrRefundExceedsTransaction, status: http.StatusUnprocessableEntity, code: CodeRefundExceedsTransaction},
+	{err: wallet.ErrConcurrentUpdate, status: http.StatusServiceUnavailable, code: CodeConcurrentUpdate},
+	{err: wallet.ErrInsufficientFunds, status: http.StatusUnprocessableEntity, code: CodeInsufficientFunds},
+}
+
+// WriteBadRequest answers with 400 on a request which failed validation.
+func WriteBadRequest(w http.ResponseWriter, err error) {
+	Write(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+}
+
+// WriteServiceError answers with the status of a known wallet service error
+// and hides details of unexpected ones behind 500.
+func WriteServiceError(ctx context.Context, w http.ResponseWriter, err error) {
+	var validationErr *wallet.ValidationError
+	if errors.As(err, &validationErr) {
+		writeValidationError(w, validationErr)
+		return
+	}
+
+	if m, ok := find(err); ok {
+		Write(w, m.status, m.code, err.Error())
+		return
+	}
+
+	logging.FromContext(ctx).Error("unexpected service error", "error", err)
+	Write(w, http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+}
+
+// writeValidationError answers with the status and code of the violation when there is only one,
+// otherwise with 422 validation_failed. Every violation is listed with its own code.
+func writeValidationError(w http.ResponseWriter, err *wallet.ValidationError) {
+	response := ErrorDTO{
+		Code:       CodeValidationFailed,
+		Message:    err.Error(),
+		Violations: make([]ViolationDTO, 0, len(err.Violations)),
+	}
+	status := http.StatusUnprocessableEntity
+	for _, v := range err.Violations {
+		m, ok := find(v.Err)
+		if !ok {
+			m = mapping{status: http.StatusBadRequest, code: CodeInvalidRequest}
+		}
+		response.Violations = append(response.Violations, ViolationDTO{Field: v.Field, Code: m.code, Message: v.Err.Error()})
+
+		if len(err.Violations) == 1 {
+			status, response.Code = m.status, m.code
+		}
+	}
+
+	write(w, status, response)
+}
+
+func find(err error) (mapping, bool) {
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return m, true
+		}
+	}
+
+	return mapping{}, false
+}
+
+func Write(w http.ResponseWriter, status int, code, message string) {
+	write(w, status, ErrorDTO{Code: code, Message: message})
+}
+
+func write(w http.ResponseWriter, status int, response ErrorDTO) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Default().Error("failed to write error message", "error", err)
+	}
+}
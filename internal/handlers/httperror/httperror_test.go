@@ -0,0 +1,98 @@
+package httperror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/wallet"
+)
+
+func TestWriteServiceError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "duplicate",
+			err:        fmt.Errorf("adding wallet into storage: %w", wallet.ErrDuplicate),
+			wantStatus: http.StatusConflict,
+			wantCode:   CodeDuplicate,
+		},
+		{
+			name:       "unknown wallet",
+			err:        fmt.Errorf("getting wallet from storage: %w", wallet.ErrWalletNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeWalletNotFound,
+		},
+		{
+			name:       "insufficient funds",
+			err:        fmt.Errorf("transferring money into storage: %w", wallet.ErrInsufficientFunds),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   CodeInsufficientFunds,
+		},
+		{
+			name:       "concurrent update",
+			err:        fmt.Errorf("transferring money into storage: %w", wallet.ErrConcurrentUpdate),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   CodeConcurrentUpdate,
+		},
+		{
+			name: "single violation",
+			err: &wallet.ValidationError{Violations: []wallet.Violation{
+				{Field: "to_wallet_id", Err: wallet.ErrWalletNotFound},
+			}},
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeWalletNotFound,
+		},
+		{
+			name: "several violations",
+			err: fmt.Errorf("wrapped: %w", &wallet.ValidationError{Violations: []wallet.Violation{
+				{Field: "value", Err: wallet.ErrInvalidAmount},
+				{Field: "to_wallet_id", Err: wallet.ErrSameWallet},
+			}}),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   CodeValidationFailed,
+		},
+		{
+			name:       "unexpected",
+			err:        fmt.Errorf("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   CodeInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			WriteServiceError(context.Background(), recorder, tt.err)
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+			var response ErrorDTO
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			require.Equal(t, tt.wantCode, response.Code)
+		})
+	}
+}
+
+func TestWriteServiceError_ListsViolations(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	WriteServiceError(context.Background(), recorder, &wallet.ValidationError{Violations: []wallet.Violation{
+		{Field: "value", Err: wallet.ErrAmountLimitExceeded},
+		{Field: "from_wallet_id", Err: wallet.ErrWalletNotFound},
+	}})
+
+	var response ErrorDTO
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	require.Equal(t, []ViolationDTO{
+		{Field: "value", Code: CodeAmountLimitExceeded, Message: wallet.ErrAmountLimitExceeded.Error()},
+		{Field: "from_wallet_id", Code: CodeWalletNotFound, Message: wallet.ErrWalletNotFound.Error()},
+	}, response.Violations)
+}
@@ -0,0 +1,54 @@
+package refund_transaction
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+// initiatorHeader optionally names the caller, it is recorded on the created transaction.
+const initiatorHeader = "X-Initiator"
+
+type walletService interface {
+	RefundTransaction(ctx context.Context, refund wallet.Refund) (int64, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	refund := wallet.Refund{
+		TransactionID:  dto.TransactionID,
+		Value:          dto.Value,
+		Initiator:      r.Header.Get(initiatorHeader),
+		IdempotencyKey: dto.IdempotencyKey,
+	}
+	transactionID, err := h.walletService.RefundTransaction(ctx, refund)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := RefundOutDTO{
+		TransactionID:         transactionID,
+		OriginalTransactionID: dto.TransactionID,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,39 @@
+package refund_transaction
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/money"
+)
+
+// RefundDTO value is in the currency of the wallet which received the original transaction,
+// an empty value refunds everything which was not refunded yet.
+type RefundDTO struct {
+	TransactionID  int64        `json:"transaction_id"`
+	Value          money.Amount `json:"value"`
+	IdempotencyKey string       `json:"idempotency_key"`
+}
+
+func validate(r *http.Request) (RefundDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var refund RefundDTO
+	if err := decoder.Decode(&refund); err != nil {
+		return RefundDTO{}, err
+	}
+
+	if refund.TransactionID == 0 {
+		return RefundDTO{}, fmt.Errorf("transaction_id is empty")
+	}
+
+	if refund.Value.Sign() < 0 {
+		return RefundDTO{}, fmt.Errorf("value is negative")
+	}
+
+	if refund.IdempotencyKey == "" {
+		return RefundDTO{}, fmt.Errorf("idempotency_key is empty")
+	}
+
+	return refund, nil
+}
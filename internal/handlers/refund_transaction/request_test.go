@@ -0,0 +1,91 @@
+package refund_transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"payment-system/internal/money"
+)
+
+func Test_validate(t *testing.T) {
+	type args struct {
+		r *http.Request
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    RefundDTO
+		wantErr bool
+	}{
+		{
+			name: "err on empty request",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("")),
+			},
+			want:    RefundDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty transaction_id",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"key\"}")),
+			},
+			want:    RefundDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on negative value",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"transaction_id\": 1, \"value\": \"-1\", \"idempotency_key\": \"key\"}")),
+			},
+			want:    RefundDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty idempotency_key",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"transaction_id\": 1}")),
+			},
+			want:    RefundDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err on full refund",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"transaction_id\": 1, \"idempotency_key\": \"key\"}")),
+			},
+			want: RefundDTO{
+				TransactionID:  1,
+				IdempotencyKey: "key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "no err on partial refund",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"transaction_id\": 1, \"value\": \"5.25\", \"idempotency_key\": \"key\"}")),
+			},
+			want: RefundDTO{
+				TransactionID:  1,
+				Value:          money.NewAmount(525, 2),
+				IdempotencyKey: "key",
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validate(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
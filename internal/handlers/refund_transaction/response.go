@@ -0,0 +1,6 @@
+package refund_transaction
+
+type RefundOutDTO struct {
+	TransactionID         int64 `json:"transaction_id"`
+	OriginalTransactionID int64 `json:"original_transaction_id"`
+}
@@ -2,14 +2,18 @@ package transfer_money
 
 import (
 	"context"
-	"log"
+	"encoding/json"
 	"net/http"
 
+	"payment-system/internal/handlers/httperror"
 	"payment-system/internal/wallet"
 )
 
+// initiatorHeader optionally names the caller, it is recorded on the created transaction.
+const initiatorHeader = "X-Initiator"
+
 type walletService interface {
-	TransferMoney(ctx context.Context, transfer wallet.Transfer) error
+	TransferMoney(ctx context.Context, transfer wallet.Transfer) (int64, error)
 }
 
 type Handler struct {
@@ -23,24 +27,29 @@ func NewHandler(walletService walletService) *Handler {
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	dto, err := validate(r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write bad request error message: %s\n", err)
-		}
+		httperror.WriteBadRequest(w, err)
 		return
 	}
 
 	ctx := r.Context()
 	transfer := wallet.Transfer{
-		FromWalletID:   dto.FromWalletID,
-		ToWalletID:     dto.ToWalletID,
-		Value:          dto.Value,
-		IdempotencyKey: dto.IdempotencyKey,
+		FromWalletID:    dto.FromWalletID,
+		ToWalletID:      dto.ToWalletID,
+		Value:           dto.Value,
+		Currency:        dto.Currency,
+		ConvertCurrency: dto.ConvertCurrency,
+		QuoteID:         dto.QuoteID,
+		Initiator:       r.Header.Get(initiatorHeader),
+		IdempotencyKey:  dto.IdempotencyKey,
 	}
-	if err := h.walletService.TransferMoney(ctx, transfer); err != nil {
+	transactionID, err := h.walletService.TransferMoney(ctx, transfer)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := TransferOutDTO{TransactionID: transactionID}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := w.Write([]byte(err.Error())); err != nil {
-			log.Printf("failed to write error message: %s\n", err)
-		}
 	}
 }
@@ -4,13 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"payment-system/internal/money"
 )
 
 type TransferDTO struct {
-	FromWalletID   int64   `json:"from_wallet_id"`
-	ToWalletID     int64   `json:"to_wallet_id"`
-	Value          float64 `json:"value"`
-	IdempotencyKey string  `json:"idempotency_key"`
+	FromWalletID    int64          `json:"from_wallet_id"`
+	ToWalletID      int64          `json:"to_wallet_id"`
+	Value           money.Amount   `json:"value"`
+	Currency        money.Currency `json:"currency,omitempty"`
+	ConvertCurrency bool           `json:"convert_currency,omitempty"`
+	QuoteID         string         `json:"quote_id,omitempty"`
+	IdempotencyKey  string         `json:"idempotency_key"`
 }
 
 func validate(r *http.Request) (TransferDTO, error) {
@@ -28,7 +33,7 @@ func validate(r *http.Request) (TransferDTO, error) {
 		return TransferDTO{}, fmt.Errorf("to_wallet_id is empty")
 	}
 
-	if transfer.Value == 0 {
+	if transfer.Value.IsZero() {
 		return TransferDTO{}, fmt.Errorf("value is empty")
 	}
 
@@ -36,5 +41,13 @@ func validate(r *http.Request) (TransferDTO, error) {
 		return TransferDTO{}, fmt.Errorf("idempotency_key is empty")
 	}
 
+	if transfer.Currency != "" {
+		currency, err := money.ParseCurrency(string(transfer.Currency))
+		if err != nil {
+			return TransferDTO{}, fmt.Errorf("currency is invalid: %w", err)
+		}
+		transfer.Currency = currency
+	}
+
 	return transfer, nil
 }
@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"payment-system/internal/money"
 )
 
 func Test_validate(t *testing.T) {
@@ -58,6 +60,29 @@ func Test_validate(t *testing.T) {
 			want:    TransferDTO{},
 			wantErr: true,
 		},
+		{
+			name: "err on unknown currency",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"from_wallet_id\": 1, \"to_wallet_id\": 2, \"value\": 1, \"idempotency_key\": \"boo\", \"currency\": \"ABC\"}")),
+			},
+			want:    TransferDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err with currency conversion",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"from_wallet_id\": 1, \"to_wallet_id\": 2, \"value\": \"15\", \"idempotency_key\": \"boo\", \"currency\": \"jpy\", \"convert_currency\": true}")),
+			},
+			want: TransferDTO{
+				FromWalletID:    1,
+				ToWalletID:      2,
+				Value:           money.NewAmount(15, 0),
+				Currency:        "JPY",
+				ConvertCurrency: true,
+				IdempotencyKey:  "boo",
+			},
+			wantErr: false,
+		},
 		{
 			name: "no err",
 			args: args{
@@ -66,7 +91,7 @@ func Test_validate(t *testing.T) {
 			want: TransferDTO{
 				FromWalletID:   1,
 				ToWalletID:     2,
-				Value:          100.53,
+				Value:          money.NewAmount(10053, 2),
 				IdempotencyKey: "boo",
 			},
 			wantErr: false,
@@ -0,0 +1,5 @@
+package transfer_money
+
+type TransferOutDTO struct {
+	TransactionID int64 `json:"transaction_id"`
+}
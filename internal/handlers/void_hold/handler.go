@@ -0,0 +1,52 @@
+package void_hold
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+type walletService interface {
+	Void(ctx context.Context, holdID int64) (wallet.Hold, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	hold, err := h.walletService.Void(ctx, dto.HoldID)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := HoldOutDTO{
+		HoldID:        hold.ID,
+		TransactionID: hold.TransactionID,
+		FromWalletID:  hold.FromWalletID,
+		ToWalletID:    hold.ToWalletID,
+		Value:         hold.Value,
+		CapturedValue: hold.CapturedValue,
+		Currency:      hold.Currency,
+		Status:        hold.Status,
+		ExpiresAt:     hold.ExpiresAt,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
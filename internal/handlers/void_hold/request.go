@@ -0,0 +1,26 @@
+package void_hold
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type VoidDTO struct {
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	HoldID         int64  `json:"hold_id"`
+}
+
+func validate(r *http.Request) (VoidDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var void VoidDTO
+	if err := decoder.Decode(&void); err != nil {
+		return VoidDTO{}, err
+	}
+
+	if void.HoldID == 0 {
+		return VoidDTO{}, fmt.Errorf("hold_id is empty")
+	}
+
+	return void, nil
+}
@@ -0,0 +1,53 @@
+package withdraw_money
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/wallet"
+)
+
+// initiatorHeader optionally names the caller, it is recorded on the created transaction.
+const initiatorHeader = "X-Initiator"
+
+type walletService interface {
+	WithdrawMoney(ctx context.Context, withdrawal wallet.Withdrawal) (int64, error)
+}
+
+type Handler struct {
+	walletService walletService
+}
+
+func NewHandler(walletService walletService) *Handler {
+	return &Handler{walletService: walletService}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dto, err := validate(r)
+	if err != nil {
+		httperror.WriteBadRequest(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	withdrawal := wallet.Withdrawal{
+		WalletID:       dto.WalletID,
+		Value:          dto.Value,
+		Currency:       dto.Currency,
+		Destination:    dto.Destination,
+		Initiator:      r.Header.Get(initiatorHeader),
+		IdempotencyKey: dto.IdempotencyKey,
+	}
+	transactionID, err := h.walletService.WithdrawMoney(ctx, withdrawal)
+	if err != nil {
+		httperror.WriteServiceError(r.Context(), w, err)
+		return
+	}
+
+	response := WithdrawalOutDTO{TransactionID: transactionID}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,57 @@
+package withdraw_money
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"payment-system/internal/money"
+)
+
+const maxDestinationLength = 255
+
+type WithdrawalDTO struct {
+	IdempotencyKey string         `json:"idempotency_key"`
+	WalletID       int64          `json:"wallet_id"`
+	Value          money.Amount   `json:"value"`
+	Currency       money.Currency `json:"currency,omitempty"`
+	Destination    string         `json:"destination"`
+}
+
+func validate(r *http.Request) (WithdrawalDTO, error) {
+	decoder := json.NewDecoder(r.Body)
+	var withdrawal WithdrawalDTO
+	if err := decoder.Decode(&withdrawal); err != nil {
+		return WithdrawalDTO{}, err
+	}
+
+	if withdrawal.IdempotencyKey == "" {
+		return WithdrawalDTO{}, fmt.Errorf("idempotency_key is empty")
+	}
+
+	if withdrawal.WalletID == 0 {
+		return WithdrawalDTO{}, fmt.Errorf("wallet_id is empty")
+	}
+
+	if withdrawal.Value.IsZero() {
+		return WithdrawalDTO{}, fmt.Errorf("value is empty")
+	}
+
+	if withdrawal.Destination == "" {
+		return WithdrawalDTO{}, fmt.Errorf("destination is empty")
+	}
+
+	if len(withdrawal.Destination) > maxDestinationLength {
+		return WithdrawalDTO{}, fmt.Errorf("destination is longer than %d", maxDestinationLength)
+	}
+
+	if withdrawal.Currency != "" {
+		currency, err := money.ParseCurrency(string(withdrawal.Currency))
+		if err != nil {
+			return WithdrawalDTO{}, fmt.Errorf("currency is invalid: %w", err)
+		}
+		withdrawal.Currency = currency
+	}
+
+	return withdrawal, nil
+}
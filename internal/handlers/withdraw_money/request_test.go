@@ -0,0 +1,97 @@
+package withdraw_money
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"payment-system/internal/money"
+)
+
+func Test_validate(t *testing.T) {
+	type args struct {
+		r *http.Request
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    WithdrawalDTO
+		wantErr bool
+	}{
+		{
+			name: "err on empty request",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("")),
+			},
+			want:    WithdrawalDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty idempotency_key",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{}")),
+			},
+			want:    WithdrawalDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty wallet_id",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\"}")),
+			},
+			want:    WithdrawalDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty value",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1}")),
+			},
+			want:    WithdrawalDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on empty destination",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1, \"value\": 10}")),
+			},
+			want:    WithdrawalDTO{},
+			wantErr: true,
+		},
+		{
+			name: "err on too long destination",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1, \"value\": 10, \"destination\": \""+strings.Repeat("a", 256)+"\"}")),
+			},
+			want:    WithdrawalDTO{},
+			wantErr: true,
+		},
+		{
+			name: "no err",
+			args: args{
+				r: httptest.NewRequest("", "/", strings.NewReader("{\"idempotency_key\": \"foo\", \"wallet_id\": 1, \"value\": \"10.05\", \"destination\": \"iban:DE89370400440532013000\"}")),
+			},
+			want: WithdrawalDTO{
+				IdempotencyKey: "foo",
+				WalletID:       1,
+				Value:          money.NewAmount(1005, 2),
+				Destination:    "iban:DE89370400440532013000",
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validate(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package withdraw_money
+
+type WithdrawalOutDTO struct {
+	TransactionID int64 `json:"transaction_id"`
+}
@@ -0,0 +1,155 @@
+// Package health serves the liveness and readiness probes of the service.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"payment-system/internal/handlers/httperror"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusShutdown    = "shutting down"
+
+	defaultCheckTimeout = 2 * time.Second
+
+	// retryAfterSeconds hints a client to retry a refused request, by then it should reach another instance.
+	retryAfterSeconds = "1"
+)
+
+// Check returns an error when a dependency of the service is not usable.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health tells the orchestrator whether the process is alive and whether it should receive traffic.
+type Health struct {
+	checkTimeout time.Duration
+
+	mu     sync.Mutex
+	checks []namedCheck
+
+	shuttingDown int32
+}
+
+type Option func(h *Health)
+
+// WithCheckTimeout bounds how long the checks of a readiness probe run.
+func WithCheckTimeout(timeout time.Duration) Option {
+	return func(h *Health) {
+		h.checkTimeout = timeout
+	}
+}
+
+func New(options ...Option) *Health {
+	h := &Health{checkTimeout: defaultCheckTimeout}
+	for _, option := range options {
+		option(h)
+	}
+
+	return h
+}
+
+// AddCheck makes readiness depend on the check.
+func (h *Health) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown makes the service not ready, so the orchestrator stops sending traffic before the server stops.
+func (h *Health) Shutdown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+func (h *Health) ShuttingDown() bool {
+	return atomic.LoadInt32(&h.shuttingDown) == 1
+}
+
+// RejectWhileShuttingDown answers 503 instead of calling next once the service is shutting down,
+// so no money move starts which could be cut off by the shutdown.
+func (h *Health) RejectWhileShuttingDown(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.ShuttingDown() {
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			w.Header().Set("Connection", "close")
+			httperror.Write(w, http.StatusServiceUnavailable, httperror.CodeShuttingDown, "service is shutting down")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness responds while the process is able to serve requests, it does not check dependencies,
+// so a lost database does not get the process restarted.
+func (h *Health) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeResponse(w, http.StatusOK, response{Status: statusOK})
+	})
+}
+
+// Readiness runs every check and responds 503 when any failed or the service is shutting down.
+func (h *Health) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.ShuttingDown() {
+			writeResponse(w, http.StatusServiceUnavailable, response{Status: statusShutdown})
+			return
+		}
+
+		h.mu.Lock()
+		checks := append([]namedCheck(nil), h.checks...)
+		h.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.checkTimeout)
+		defer cancel()
+
+		results := make([]string, len(checks))
+		var wg sync.WaitGroup
+		for i, c := range checks {
+			wg.Add(1)
+			go func(i int, c namedCheck) {
+				defer wg.Done()
+
+				results[i] = statusOK
+				if err := c.check(ctx); err != nil {
+					results[i] = err.Error()
+				}
+			}(i, c)
+		}
+		wg.Wait()
+
+		resp := response{Status: statusOK, Checks: make(map[string]string, len(checks))}
+		statusCode := http.StatusOK
+		for i, c := range checks {
+			resp.Checks[c.name] = results[i]
+			if results[i] != statusOK {
+				resp.Status = statusUnavailable
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+		writeResponse(w, statusCode, resp)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/handlers/httperror"
+)
+
+func TestLiveness(t *testing.T) {
+	h := New()
+	h.AddCheck("database", func(context.Context) error { return errors.New("connection refused") })
+	h.Shutdown()
+
+	recorder := httptest.NewRecorder()
+	h.Liveness().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status": "ok"}`, recorder.Body.String())
+}
+
+func TestReadiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	tests := []struct {
+		name     string
+		checks   map[string]Check
+		shutdown bool
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "no checks",
+			wantCode: http.StatusOK,
+			wantBody: `{"status": "ok"}`,
+		},
+		{
+			name:     "all checks pass",
+			checks:   map[string]Check{"database": ok, "migrations": ok},
+			wantCode: http.StatusOK,
+			wantBody: `{"status": "ok", "checks": {"database": "ok", "migrations": "ok"}}`,
+		},
+		{
+			name: "failed check",
+			checks: map[string]Check{
+				"database":   ok,
+				"migrations": func(context.Context) error { return errors.New("schema version is 11, expected 12") },
+			},
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"status": "unavailable", "checks": {"database": "ok", "migrations": "schema version is 11, expected 12"}}`,
+		},
+		{
+			name:     "shutting down",
+			checks:   map[string]Check{"database": ok},
+			shutdown: true,
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"status": "shutting down"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New()
+			for name, check := range tt.checks {
+				h.AddCheck(name, check)
+			}
+			if tt.shutdown {
+				h.Shutdown()
+			}
+
+			recorder := httptest.NewRecorder()
+			h.Readiness().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, tt.wantCode, recorder.Code)
+			require.JSONEq(t, tt.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestReadiness_TimesOutChecks(t *testing.T) {
+	h := New(WithCheckTimeout(10 * time.Millisecond))
+	h.AddCheck("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	recorder := httptest.NewRecorder()
+	h.Readiness().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var resp response
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, context.DeadlineExceeded.Error(), resp.Checks["database"])
+}
+
+func TestRejectWhileShuttingDown(t *testing.T) {
+	h := New()
+	calls := 0
+	handler := h.RejectWhileShuttingDown(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/depositMoney", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, 1, calls)
+
+	h.Shutdown()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/depositMoney", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	require.Equal(t, 1, calls)
+
+	var errorDTO httperror.ErrorDTO
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errorDTO))
+	require.Equal(t, httperror.CodeShuttingDown, errorDTO.Code)
+}
@@ -0,0 +1,261 @@
+//go:generate mockgen -source=middleware.go -destination mock.go -package $GOPACKAGE
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/logging"
+	"payment-system/internal/storage"
+)
+
+const (
+	DefaultWindow = 24 * time.Hour
+	// DefaultPendingTimeout bounds how long a request may run before a retry with its key is served again,
+	// e.g. when the process was killed while serving it.
+	DefaultPendingTimeout = time.Minute
+
+	// MaxKeyLength is the length of the idempotency keys the storage keeps, e.g. of a UUID.
+	MaxKeyLength = 36
+
+	// ReplayedHeader marks a response which was saved for an earlier request with the same key.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+type idempotencyStorage interface {
+	ReserveIdempotencyKey(ctx context.Context, key storage.IdempotencyKey, now time.Time) (storage.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key storage.IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, key storage.IdempotencyKey) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ConflictObserver is notified of requests rejected for their idempotency key, e.g. to export metrics.
+type ConflictObserver interface {
+	ObserveConflict(endpoint, code string)
+}
+
+// Middleware makes retries of a request with the same idempotency_key safe:
+// an identical retry gets the original successful response, a retry with another payload gets 422.
+// Keys expire after the window.
+type Middleware struct {
+	storage        idempotencyStorage
+	window         time.Duration
+	pendingTimeout time.Duration
+	observer       ConflictObserver
+	now            func() time.Time
+}
+
+type Option func(*Middleware)
+
+// WithObserver reports conflicting requests to the observer.
+func WithObserver(observer ConflictObserver) Option {
+	return func(m *Middleware) {
+		m.observer = observer
+	}
+}
+
+// WithPendingTimeout sets how long a request may run before a retry with its key is served again.
+func WithPendingTimeout(timeout time.Duration) Option {
+	return func(m *Middleware) {
+		m.pendingTimeout = timeout
+	}
+}
+
+func New(storage idempotencyStorage, window time.Duration, options ...Option) *Middleware {
+	m := &Middleware{
+		storage:        storage,
+		window:         window,
+		pendingTimeout: DefaultPendingTimeout,
+		now:            time.Now,
+	}
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+// Wrap makes the endpoint idempotent. A key is unique within the values of the scope fields of the body,
+// e.g. the wallet the request acts on, so different wallets may use the same key.
+func (m *Middleware) Wrap(endpoint string, next http.Handler, scope ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httperror.WriteBadRequest(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		reserved, ok := parse(body, scope)
+		if ok && len(reserved.Key) > MaxKeyLength {
+			httperror.WriteBadRequest(w, fmt.Errorf("idempotency_key is longer than %d characters", MaxKeyLength))
+			return
+		}
+		if !ok {
+			// let the handler reject the request
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		now := m.now()
+		reserved.Endpoint = endpoint
+		reserved.ReservedUntil = now.Add(m.pendingTimeout)
+		reserved.ExpiresAt = now.Add(m.window)
+		saved, ok, err := m.storage.ReserveIdempotencyKey(ctx, reserved, now)
+		if err != nil {
+			httperror.WriteServiceError(ctx, w, err)
+			return
+		}
+
+		if !ok {
+			if code := replay(ctx, w, saved, reserved.Fingerprint); code != "" && m.observer != nil {
+				m.observer.ObserveConflict(endpoint, code)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		defer func() {
+			// the response is saved even if the client has gone already
+			logger := logging.FromContext(ctx)
+			ctx := context.Background()
+			if recorder.statusCode >= 200 && recorder.statusCode < 300 {
+				reserved.StatusCode = sql.NullInt32{Int32: int32(recorder.statusCode), Valid: true}
+				reserved.ContentType = sql.NullString{String: w.Header().Get("Content-Type"), Valid: true}
+				reserved.ResponseBody = recorder.body.Bytes()
+				if err := m.storage.CompleteIdempotencyKey(ctx, reserved); err != nil {
+					logger.Error("failed to complete idempotency key", "error", err)
+				}
+				return
+			}
+
+			if err := m.storage.ReleaseIdempotencyKey(ctx, reserved); err != nil {
+				logger.Error("failed to release idempotency key", "error", err)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// SweepKeys deletes expired keys every interval until the context is done.
+func (m *Middleware) SweepKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := m.storage.DeleteExpiredIdempotencyKeys(ctx, m.now())
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to delete expired idempotency keys", "error", err)
+			continue
+		}
+
+		if deleted > 0 {
+			logging.FromContext(ctx).Info("deleted expired idempotency keys", "count", deleted)
+		}
+	}
+}
+
+// replay writes the saved response, it returns the error code when the request conflicts with the saved one.
+func replay(ctx context.Context, w http.ResponseWriter, saved storage.IdempotencyKey, fingerprint string) string {
+	if saved.Fingerprint != fingerprint {
+		httperror.Write(w, http.StatusUnprocessableEntity, httperror.CodeIdempotencyKeyReused,
+			"idempotency_key was used for another request")
+		return httperror.CodeIdempotencyKeyReused
+	}
+
+	if !saved.StatusCode.Valid {
+		httperror.Write(w, http.StatusConflict, httperror.CodeRequestInProgress,
+			"request with the idempotency_key is in progress")
+		return httperror.CodeRequestInProgress
+	}
+
+	if saved.ContentType.String != "" {
+		w.Header().Set("Content-Type", saved.ContentType.String)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(int(saved.StatusCode.Int32))
+	if _, err := w.Write(saved.ResponseBody); err != nil {
+		logging.FromContext(ctx).Error("failed to write replayed response", "error", err)
+	}
+
+	return ""
+}
+
+// parse returns the idempotency key, its scope and the fingerprint of the JSON body.
+// The fingerprint does not depend on formatting and order of fields.
+func parse(body []byte, scope []string) (storage.IdempotencyKey, bool) {
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return storage.IdempotencyKey{}, false
+	}
+
+	key, _ := payload["idempotency_key"].(string)
+	if key == "" {
+		return storage.IdempotencyKey{}, false
+	}
+
+	canonical, err := json.Marshal(payload)
+	if err != nil {
+		return storage.IdempotencyKey{}, false
+	}
+
+	values := make([]string, 0, len(scope))
+	for _, field := range scope {
+		switch value := payload[field].(type) {
+		case string:
+			values = append(values, value)
+		case json.Number:
+			values = append(values, value.String())
+		default:
+			values = append(values, "")
+		}
+	}
+
+	sum := sha256.Sum256(canonical)
+	return storage.IdempotencyKey{
+		Scope:       strings.Join(values, "/"),
+		Key:         key,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}, true
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
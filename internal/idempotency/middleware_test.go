@@ -0,0 +1,188 @@
+package idempotency
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/storage"
+)
+
+func TestMiddleware_SavesSuccessfulResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockidempotencyStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	body := `{"idempotency_key": "foo", "wallet_id": 7, "value": 1}`
+	parsed, _ := parse([]byte(body), nil)
+	reserved := storage.IdempotencyKey{
+		Endpoint:      "deposit",
+		Scope:         "7",
+		Key:           "foo",
+		Fingerprint:   parsed.Fingerprint,
+		ReservedUntil: now.Add(DefaultPendingTimeout),
+		ExpiresAt:     now.Add(time.Hour),
+	}
+	mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), reserved, now).Return(reserved, true, nil)
+	completed := reserved
+	completed.StatusCode = sql.NullInt32{Int32: http.StatusOK, Valid: true}
+	completed.ContentType = sql.NullString{String: "application/json", Valid: true}
+	completed.ResponseBody = []byte(`{"id":1}`)
+	mockStorage.EXPECT().CompleteIdempotencyKey(gomock.Any(), completed).Return(nil)
+
+	middleware := New(mockStorage, time.Hour)
+	middleware.now = func() time.Time { return now }
+	handler := middleware.Wrap("deposit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}), "wallet_id")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("", "/", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `{"id":1}`, recorder.Body.String())
+}
+
+func TestMiddleware_ReleasesKeyOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockidempotencyStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	body := `{"idempotency_key": "foo", "wallet_id": 7}`
+	released, _ := parse([]byte(body), []string{"wallet_id"})
+	released.Endpoint, released.ReservedUntil, released.ExpiresAt = "deposit", now.Add(DefaultPendingTimeout), now.Add(time.Hour)
+	mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any(), gomock.Any()).Return(storage.IdempotencyKey{}, true, nil)
+	mockStorage.EXPECT().ReleaseIdempotencyKey(gomock.Any(), released).Return(nil)
+
+	middleware := New(mockStorage, time.Hour)
+	middleware.now = func() time.Time { return now }
+	handler := middleware.Wrap("deposit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}), "wallet_id")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("", "/", strings.NewReader(body)))
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+}
+
+func TestMiddleware_ReplaysIdenticalRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockidempotencyStorage(ctrl)
+	parsed, _ := parse([]byte(`{"value": 1, "idempotency_key": "foo"}`), nil)
+	mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any(), gomock.Any()).Return(storage.IdempotencyKey{
+		Endpoint:     "deposit",
+		Key:          "foo",
+		Fingerprint:  parsed.Fingerprint,
+		StatusCode:   sql.NullInt32{Int32: http.StatusOK, Valid: true},
+		ContentType:  sql.NullString{String: "application/json", Valid: true},
+		ResponseBody: []byte(`{"id":1}`),
+	}, false, nil)
+
+	handler := New(mockStorage, time.Hour).Wrap("deposit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("replayed request must not reach the handler")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("", "/", strings.NewReader(`{"idempotency_key":"foo","value":1}`)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `{"id":1}`, recorder.Body.String())
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "true", recorder.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_RejectsReusedKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		saved      storage.IdempotencyKey
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "another payload",
+			saved:      storage.IdempotencyKey{Fingerprint: "another"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   httperror.CodeIdempotencyKeyReused,
+		},
+		{
+			name:       "in progress",
+			wantStatus: http.StatusConflict,
+			wantCode:   httperror.CodeRequestInProgress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockStorage := NewMockidempotencyStorage(ctrl)
+			body := `{"idempotency_key": "foo", "value": 2}`
+			saved := tt.saved
+			if saved.Fingerprint == "" {
+				parsed, _ := parse([]byte(body), nil)
+				saved.Fingerprint = parsed.Fingerprint
+			}
+			mockStorage.EXPECT().ReserveIdempotencyKey(gomock.Any(), gomock.Any(), gomock.Any()).Return(saved, false, nil)
+			mockObserver := NewMockConflictObserver(ctrl)
+			mockObserver.EXPECT().ObserveConflict("deposit", tt.wantCode)
+
+			handler := New(mockStorage, time.Hour, WithObserver(mockObserver)).Wrap("deposit", http.NotFoundHandler())
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("", "/", strings.NewReader(body)))
+			require.Equal(t, tt.wantStatus, recorder.Code)
+
+			var response httperror.ErrorDTO
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+			require.Equal(t, tt.wantCode, response.Code)
+		})
+	}
+}
+
+func TestMiddleware_PassesRequestWithoutKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockidempotencyStorage(ctrl)
+	handler := New(mockStorage, time.Hour).Wrap("deposit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("", "/", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestMiddleware_RejectsLongKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockidempotencyStorage(ctrl)
+	handler := New(mockStorage, time.Hour).Wrap("deposit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request with a long key must not reach the handler")
+	}))
+
+	body := `{"idempotency_key": "` + strings.Repeat("a", MaxKeyLength+1) + `"}`
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("", "/", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func Test_parse(t *testing.T) {
+	scope := []string{"wallet_id"}
+	key, ok := parse([]byte(`{"idempotency_key": "foo", "value": 100.10, "wallet_id": 1}`), scope)
+	require.True(t, ok)
+	require.Equal(t, "foo", key.Key)
+	require.Equal(t, "1", key.Scope)
+
+	reordered, _ := parse([]byte(`{"wallet_id":1,"value":100.10,"idempotency_key":"foo"}`), scope)
+	require.Equal(t, key.Fingerprint, reordered.Fingerprint)
+
+	changed, _ := parse([]byte(`{"wallet_id":1,"value":100.1,"idempotency_key":"foo"}`), scope)
+	require.NotEqual(t, key.Fingerprint, changed.Fingerprint)
+
+	other, _ := parse([]byte(`{"wallet_id":2,"value":100.10,"idempotency_key":"foo"}`), scope)
+	require.Equal(t, key.Key, other.Key)
+	require.NotEqual(t, key.Scope, other.Scope)
+
+	_, ok = parse([]byte(`not json`), scope)
+	require.False(t, ok)
+}
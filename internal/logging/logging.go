@@ -0,0 +1,168 @@
+// Package logging writes structured JSON logs. A logger carrying the request id travels in the context,
+// so every layer logs with the request it serves.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Redaction hides sensitive values marked with Amount and Key.
+type Redaction struct {
+	Amounts bool
+	Keys    bool
+}
+
+type output struct {
+	mu        sync.Mutex
+	w         io.Writer
+	redaction Redaction
+	now       func() time.Time
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Logger writes one JSON object per line: time, level, msg and the fields in order they were added.
+type Logger struct {
+	out    *output
+	fields []field
+}
+
+func New(w io.Writer, redaction Redaction) *Logger {
+	return &Logger{out: &output{w: w, redaction: redaction, now: time.Now}}
+}
+
+// With returns a logger which adds the key value pairs to every entry.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]field, 0, len(l.fields)+len(keyvals)/2)
+	fields = append(fields, l.fields...)
+	return &Logger{out: l.out, fields: appendFields(fields, keyvals)}
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.write("info", msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.write("warn", msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.write("error", msg, keyvals)
+}
+
+func (l *Logger) write(level, msg string, keyvals []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	encode(&buf, l.out.now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	encode(&buf, level)
+	buf.WriteString(`,"msg":`)
+	encode(&buf, msg)
+	for _, f := range appendFields(l.fields, keyvals) {
+		buf.WriteByte(',')
+		encode(&buf, f.key)
+		buf.WriteByte(':')
+		encode(&buf, l.out.redact(f.value))
+	}
+	buf.WriteString("}\n")
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.w.Write(buf.Bytes())
+}
+
+func (o *output) redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case amount:
+		if o.redaction.Amounts {
+			return redacted
+		}
+		return v.value
+	case key:
+		if o.redaction.Keys {
+			return redacted
+		}
+		return v.value
+	case error:
+		return v.Error()
+	case json.Marshaler, json.Number:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+type amount struct {
+	value interface{}
+}
+
+type key struct {
+	value interface{}
+}
+
+// Amount marks a money amount, it is hidden when amounts are redacted.
+func Amount(value interface{}) interface{} {
+	return amount{value: value}
+}
+
+// Key marks an idempotency key or another secret-like value, it is hidden when keys are redacted.
+func Key(value interface{}) interface{} {
+	return key{value: value}
+}
+
+// appendFields pairs keyvals up, a key without a value gets null.
+func appendFields(fields []field, keyvals []interface{}) []field {
+	for i := 0; i < len(keyvals); i += 2 {
+		f := field{key: fmt.Sprint(keyvals[i])}
+		if i+1 < len(keyvals) {
+			f.value = keyvals[i+1]
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+func encode(buf *bytes.Buffer, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(data)
+}
+
+type contextKey struct{}
+
+var std = New(os.Stderr, Redaction{})
+
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// Default returns the logger writing to stderr, it serves code which has no context.
+func Default() *Logger {
+	return std
+}
+
+// FromContext returns the logger of the context or a logger writing to stderr.
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return logger
+	}
+
+	return std
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/money"
+)
+
+func newTestLogger(redaction Redaction) (*Logger, *bytes.Buffer) {
+	var out bytes.Buffer
+	logger := New(&out, redaction)
+	logger.out.now = func() time.Time { return time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC) }
+	return logger, &out
+}
+
+func TestLogger_WritesJSONLines(t *testing.T) {
+	logger, out := newTestLogger(Redaction{})
+	logger.With("request_id", "abc").Error("failed", "error", errors.New("boom"), "wallet_id", int64(1), "odd")
+
+	require.Equal(t, `{"time":"2021-07-01T12:00:00Z","level":"error","msg":"failed","request_id":"abc",`+
+		`"error":"boom","wallet_id":1,"odd":null}`+"\n", out.String())
+}
+
+func TestLogger_RedactsMarkedValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		redaction Redaction
+		want      string
+	}{
+		{
+			name: "nothing",
+			want: `"value":"10.50","idempotency_key":"foo"`,
+		},
+		{
+			name:      "amounts",
+			redaction: Redaction{Amounts: true},
+			want:      `"value":"[REDACTED]","idempotency_key":"foo"`,
+		},
+		{
+			name:      "keys",
+			redaction: Redaction{Keys: true},
+			want:      `"value":"10.50","idempotency_key":"[REDACTED]"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newTestLogger(tt.redaction)
+			logger.Info("deposit", "value", Amount(money.NewAmount(1050, 2)), "idempotency_key", Key("foo"))
+			require.Contains(t, out.String(), tt.want)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	logger, _ := newTestLogger(Redaction{})
+	require.Same(t, logger, FromContext(NewContext(context.Background(), logger)))
+	require.Same(t, Default(), FromContext(context.Background()))
+}
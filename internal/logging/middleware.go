@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the id of a request from the client and back in the response.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// maxLoggedBodyLength bounds the part of the body read for the access log, fields of longer bodies
+// are not logged and the body is passed on unread.
+const maxLoggedBodyLength = 64 << 10
+
+// loggedFields are request fields which identify wallets and operations in the access log.
+var loggedFields = []string{"wallet_id", "from_wallet_id", "to_wallet_id", "transaction_id", "hold_id"}
+
+type requestIDKey struct{}
+
+type requestValuesKey struct{}
+
+// requestValues are the fields of a request for its access log entry. They are read from the query
+// and the body before the request is served, handlers add the ones which they take from elsewhere.
+type requestValues struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// SetRequestField adds a field of the request to its access log entry, e.g. a path parameter
+// which the router moves into the body after the access log has read it.
+func SetRequestField(ctx context.Context, name string, value interface{}) {
+	values, ok := ctx.Value(requestValuesKey{}).(*requestValues)
+	if !ok {
+		return
+	}
+
+	values.mu.Lock()
+	defer values.mu.Unlock()
+	values.values[name] = value
+}
+
+// RequestID returns the id of the request which the context serves.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// AccessLog assigns every request an id, passes the logger with the id in the request context
+// and logs the request when it is served.
+func AccessLog(logger *Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		requestLogger := logger.With("request_id", id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = NewContext(ctx, requestLogger)
+		values := &requestValues{values: readRequestValues(r)}
+		ctx = context.WithValue(ctx, requestValuesKey{}, values)
+
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		keyvals := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.statusCode,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		}
+		requestLogger.Info("request", append(keyvals, values.fields()...)...)
+	})
+}
+
+// readRequestValues reads the query and the JSON body of the request.
+func readRequestValues(r *http.Request) map[string]interface{} {
+	values := make(map[string]interface{})
+	for name, query := range r.URL.Query() {
+		if len(query) > 0 {
+			values[name] = query[0]
+		}
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxLoggedBodyLength+1))
+		if err == nil && len(body) <= maxLoggedBodyLength {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			_ = decoder.Decode(&values)
+		}
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	}
+
+	return values
+}
+
+// fields picks wallet ids, the idempotency key and the amount of the request.
+func (v *requestValues) fields() []interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	values := v.values
+
+	var fields []interface{}
+	for _, name := range loggedFields {
+		if value, ok := values[name]; ok {
+			fields = append(fields, name, value)
+		}
+	}
+	if value, ok := values["idempotency_key"]; ok {
+		fields = append(fields, "idempotency_key", Key(value))
+	}
+	if value, ok := values["value"]; ok {
+		fields = append(fields, "value", Amount(value))
+	}
+
+	return fields
+}
+
+// validRequestID accepts ids of clients which are safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readCloser passes on the rest of a body whose beginning was read already.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	logger, out := newTestLogger(Redaction{Amounts: true})
+	var requestID string
+	handler := AccessLog(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = RequestID(r.Context())
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), "idempotency_key")
+
+		FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	body := `{"from_wallet_id": 1, "to_wallet_id": 2, "value": "10.50", "idempotency_key": "foo"}`
+	request := httptest.NewRequest(http.MethodPost, "/transferMoney", strings.NewReader(body))
+	request.Header.Set(RequestIDHeader, "abc")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, "abc", requestID)
+	require.Equal(t, "abc", recorder.Header().Get(RequestIDHeader))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `"msg":"handled","request_id":"abc"`)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	require.Equal(t, "request", entry["msg"])
+	require.Equal(t, "abc", entry["request_id"])
+	require.Equal(t, "POST", entry["method"])
+	require.Equal(t, "/transferMoney", entry["path"])
+	require.Equal(t, float64(http.StatusCreated), entry["status"])
+	require.Equal(t, float64(1), entry["from_wallet_id"])
+	require.Equal(t, float64(2), entry["to_wallet_id"])
+	require.Equal(t, "foo", entry["idempotency_key"])
+	require.Equal(t, "[REDACTED]", entry["value"])
+	require.Contains(t, entry, "latency_ms")
+}
+
+func TestAccessLog_LogsFieldsSetByHandler(t *testing.T) {
+	logger, out := newTestLogger(Redaction{})
+	handler := AccessLog(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRequestField(r.Context(), "wallet_id", json.Number("7"))
+		SetRequestField(r.Context(), "format", "csv")
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/wallets/7", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	require.Equal(t, float64(7), entry["wallet_id"])
+	require.NotContains(t, entry, "format", "only fields which identify wallets and operations are logged")
+}
+
+func TestAccessLog_PassesLongBodyOn(t *testing.T) {
+	logger, out := newTestLogger(Redaction{})
+	body := `{"wallet_id": 1, "reference": "` + strings.Repeat("a", maxLoggedBodyLength) + `"}`
+	handler := AccessLog(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, body, string(got))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/depositMoney", strings.NewReader(body)))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	require.NotContains(t, entry, "wallet_id", "fields of a long body are not logged")
+}
+
+func TestAccessLog_ReplacesInvalidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "missing"},
+		{name: "with spaces", id: "a b"},
+		{name: "too long", id: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := newTestLogger(Redaction{})
+			handler := AccessLog(logger, http.NotFoundHandler())
+			request := httptest.NewRequest(http.MethodGet, "/getBalance?wallet_id=1", nil)
+			request.Header.Set(RequestIDHeader, tt.id)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			id := recorder.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, id)
+			require.NotEqual(t, tt.id, id)
+		})
+	}
+}
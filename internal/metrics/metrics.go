@@ -0,0 +1,148 @@
+// Package metrics exposes metrics of the service at /metrics in the Prometheus text format.
+// Collectors are written by hand, so the service has no dependency on the Prometheus client.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"payment-system/internal/money"
+	"payment-system/internal/storage"
+	"payment-system/internal/wallet"
+)
+
+// amountBuckets suit amounts in major units of any currency.
+var amountBuckets = []float64{1, 10, 100, 1000, 10000, 100000, 1000000}
+
+// Metrics of the HTTP API, wallet operations and the database pool.
+type Metrics struct {
+	registry *Registry
+
+	requests             *CounterVec
+	requestDuration      *HistogramVec
+	operations           *CounterVec
+	operationAmount      *HistogramVec
+	insufficientFunds    *CounterVec
+	idempotencyConflicts *CounterVec
+}
+
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		registry: r,
+		requests: r.NewCounter("http_requests_total",
+			"Count of HTTP requests by handler and status code.", "handler", "code"),
+		requestDuration: r.NewHistogram("http_request_duration_seconds",
+			"Latency of HTTP requests by handler and status code.", DefaultBuckets, "handler", "code"),
+		operations: r.NewCounter("wallet_operations_total",
+			"Count of money moves by operation type and result.", "operation", "result"),
+		operationAmount: r.NewHistogram("wallet_operation_amount",
+			"Amounts of successful money moves in major units of the wallet currency.", amountBuckets, "operation", "currency"),
+		insufficientFunds: r.NewCounter("wallet_insufficient_funds_total",
+			"Count of money moves rejected for insufficient funds by operation type.", "operation"),
+		idempotencyConflicts: r.NewCounter("idempotency_conflicts_total",
+			"Count of requests rejected for a reused or in-progress idempotency key.", "endpoint", "code"),
+	}
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return m.registry.Handler()
+}
+
+// Instrument counts requests to the handler and measures their latency.
+func (m *Metrics) Instrument(handler string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		code := strconv.Itoa(recorder.statusCode)
+		m.requests.Inc(handler, code)
+		m.requestDuration.Observe(time.Since(start).Seconds(), handler, code)
+	})
+}
+
+// ObserveOperation implements wallet.OperationObserver. Zero amount is not observed, it means a full
+// capture or refund of an unknown amount.
+func (m *Metrics) ObserveOperation(operation string, currency money.Currency, amount money.Amount, err error) {
+	m.operations.Inc(operation, result(err))
+	if errors.Is(err, storage.ErrInsufficientFunds) {
+		m.insufficientFunds.Inc(operation)
+	}
+
+	if err != nil || amount.IsZero() {
+		return
+	}
+
+	value, parseErr := strconv.ParseFloat(amount.String(), 64)
+	if parseErr != nil {
+		return
+	}
+	m.operationAmount.Observe(value, operation, string(currency))
+}
+
+// ObserveConflict implements idempotency.ConflictObserver.
+func (m *Metrics) ObserveConflict(endpoint, code string) {
+	m.idempotencyConflicts.Inc(endpoint, code)
+}
+
+// RegisterDBStats exposes the connection pool statistics of the database.
+func (m *Metrics) RegisterDBStats(db *sql.DB) {
+	gauge := func(name, help string, f func(s sql.DBStats) float64) {
+		m.registry.NewGaugeFunc(name, help, func() float64 {
+			return f(db.Stats())
+		})
+	}
+	counter := func(name, help string, f func(s sql.DBStats) float64) {
+		m.registry.NewCounterFunc(name, help, func() float64 {
+			return f(db.Stats())
+		})
+	}
+
+	gauge("db_max_open_connections", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("db_open_connections", "Number of established connections both in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("db_in_use_connections", "Number of connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("db_idle_connections", "Number of idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("db_wait_count_total", "Total number of connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("db_max_idle_closed_total", "Total number of connections closed due to max idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("db_max_idle_time_closed_total", "Total number of connections closed due to max idle time.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("db_max_lifetime_closed_total", "Total number of connections closed due to max lifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		return "insufficient_funds"
+	case errors.Is(err, storage.ErrDuplicate):
+		return "duplicate"
+	case errors.Is(err, wallet.ErrValidation):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
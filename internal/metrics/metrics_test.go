@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/money"
+	"payment-system/internal/storage"
+)
+
+func TestMetrics_InstrumentsHandler(t *testing.T) {
+	m := New()
+	handler := m.Instrument("/depositMoney", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/depositMoney", nil))
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, recorder.Body.String(), `http_requests_total{handler="/depositMoney",code="404"} 1`)
+	require.Contains(t, recorder.Body.String(), `http_request_duration_seconds_count{handler="/depositMoney",code="404"} 1`)
+}
+
+func TestMetrics_ObservesOperations(t *testing.T) {
+	m := New()
+	m.ObserveOperation("deposit", "USD", money.NewAmount(1050, 2), nil)
+	m.ObserveOperation("transfer", "USD", money.NewAmount(5, 0), fmt.Errorf("transferring: %w", storage.ErrInsufficientFunds))
+	m.ObserveConflict("depositMoney", "idempotency_key_reused")
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+	require.Contains(t, body, `wallet_operations_total{operation="deposit",result="success"} 1`)
+	require.Contains(t, body, `wallet_operations_total{operation="transfer",result="insufficient_funds"} 1`)
+	require.Contains(t, body, `wallet_operation_amount_sum{operation="deposit",currency="USD"} 10.5`)
+	require.NotContains(t, body, `wallet_operation_amount_count{operation="transfer"`)
+	require.Contains(t, body, `wallet_insufficient_funds_total{operation="transfer"} 1`)
+	require.Contains(t, body, `idempotency_conflicts_total{endpoint="depositMoney",code="idempotency_key_reused"} 1`)
+}
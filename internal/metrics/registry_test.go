@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WritesTextFormat(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Count of requests.", "handler", "code")
+	duration := r.NewHistogram("duration_seconds", "Latency of requests.", []float64{0.1, 1}, "handler")
+	r.NewGaugeFunc("connections", "Open connections.", func() float64 { return 3 })
+
+	requests.Inc("/b", "200")
+	requests.Add(2, "/a", "500")
+	requests.Inc(`/"quoted"`, "200")
+	duration.Observe(0.05, "/a")
+	duration.Observe(0.5, "/a")
+	duration.Observe(5, "/a")
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out))
+	require.Equal(t, `# HELP requests_total Count of requests.
+# TYPE requests_total counter
+requests_total{handler="/\"quoted\"",code="200"} 1
+requests_total{handler="/a",code="500"} 2
+requests_total{handler="/b",code="200"} 1
+# HELP duration_seconds Latency of requests.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{handler="/a",le="0.1"} 1
+duration_seconds_bucket{handler="/a",le="1"} 2
+duration_seconds_bucket{handler="/a",le="+Inf"} 3
+duration_seconds_sum{handler="/a"} 5.55
+duration_seconds_count{handler="/a"} 3
+# HELP connections Open connections.
+# TYPE connections gauge
+connections 3
+`, out.String())
+}
+
+func TestCounterVec_PanicsOnWrongLabels(t *testing.T) {
+	requests := NewRegistry().NewCounter("requests_total", "Count of requests.", "handler", "code")
+	require.Panics(t, func() {
+		requests.Inc("/a")
+	})
+}
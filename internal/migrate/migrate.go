@@ -0,0 +1,285 @@
+// Package migrate applies the SQL migrations embedded into the binary. The applied version is kept in
+// schema_migrations the way golang-migrate keeps it, so databases migrated by the migrate tool carry on.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+
+	"payment-system/internal/logging"
+)
+
+// lockID is the key of the advisory lock held while migrating, so replicas starting together
+// apply every migration once.
+const lockID int64 = 4206090715
+
+// undefinedTableCode is reported when schema_migrations does not exist yet.
+const undefinedTableCode = "42P01"
+
+const (
+	createVersionTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`
+	selectVersionQuery      = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+	deleteVersionQuery      = `DELETE FROM schema_migrations`
+	insertVersionQuery      = `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`
+)
+
+var (
+	ErrDirty         = errors.New("schema is dirty")
+	ErrUnknownSchema = errors.New("schema version is unknown")
+
+	fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+type Migration struct {
+	Version uint
+	Name    string
+	up      string
+	down    string
+}
+
+// Status of the schema, version zero means no migration is applied.
+type Status struct {
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Pending []Migration
+}
+
+// Load reads migrations of files named like 000001_create_tables.up.sql, every migration has
+// an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("listing migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid version of migration %s", entry.Name())
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[m.Version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m.Name, match[2], version)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading migration: %w", err)
+		}
+		if match[3] == "up" {
+			m.up = string(data)
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up or down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func New(db *sqlx.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest returns the version of the last migration, it is the version the service expects.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	version, dirty, err := readVersion(ctx, m.db)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Version: version, Dirty: dirty, Latest: m.Latest()}
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+
+	return status, nil
+}
+
+// Up applies every pending migration and returns them.
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn, version uint) error {
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+
+			if err := apply(ctx, conn, migration.up, migration.Version); err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			logging.FromContext(ctx).Info("applied migration", "version", migration.Version, "name", migration.Name)
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn, version uint) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+
+			var previous uint
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := apply(ctx, conn, migration.down, previous); err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			logging.FromContext(ctx).Info("reverted migration", "version", migration.Version, "name", migration.Name)
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// locked runs fn on a connection holding the advisory lock, other replicas wait until it is released.
+// fn gets the version of the schema read after the lock was acquired.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, version uint) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			logging.FromContext(ctx).Error("failed to release migration lock", "error", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createVersionTableQuery); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w: migration %d failed halfway, fix the schema and its version by hand", ErrDirty, version)
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("%w: %d is newer than the last migration %d", ErrUnknownSchema, version, m.Latest())
+	}
+
+	return fn(conn, version)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func readVersion(ctx context.Context, q queryer) (version uint, dirty bool, err error) {
+	err = q.QueryRowContext(ctx, selectVersionQuery).Scan(&version, &dirty)
+	var pgErr pgx.PgError
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == undefinedTableCode) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("selecting schema version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// apply runs the migration and records the version in one transaction, so a failed migration
+// leaves neither changes nor a dirty version behind.
+func apply(ctx context.Context, conn *sql.Conn, query string, version uint) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning migration tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback migration tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting migration tx: %w", err)
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, deleteVersionQuery); err != nil {
+		return fmt.Errorf("deleting schema version: %w", err)
+	}
+	if version > 0 {
+		if _, err = tx.ExecContext(ctx, insertVersionQuery, version); err != nil {
+			return fmt.Errorf("inserting schema version: %w", err)
+		}
+	}
+
+	return nil
+}
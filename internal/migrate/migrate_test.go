@@ -0,0 +1,247 @@
+package migrate
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"payment-system/db/migrations"
+	"payment-system/internal/config"
+	"payment-system/internal/db"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(data string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data)}
+	}
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		wantVersions []uint
+		wantErr      string
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"000010_add_refund.up.sql":      file("ALTER TABLE"),
+				"000010_add_refund.down.sql":    file("ALTER TABLE"),
+				"000002_add_currency.up.sql":    file("ALTER TABLE"),
+				"000002_add_currency.down.sql":  file("ALTER TABLE"),
+				"000001_create_tables.up.sql":   file("CREATE TABLE"),
+				"000001_create_tables.down.sql": file("DROP TABLE"),
+				"migrations.go":                 file("package migrations"),
+			},
+			wantVersions: []uint{1, 2, 10},
+		},
+		{
+			name:    "missing down file",
+			fsys:    fstest.MapFS{"000001_create_tables.up.sql": file("CREATE TABLE")},
+			wantErr: "migration 1_create_tables has no up or down file",
+		},
+		{
+			name: "shared version",
+			fsys: fstest.MapFS{
+				"000001_create_tables.up.sql": file("CREATE TABLE"),
+				"000001_create_wallet.up.sql": file("CREATE TABLE"),
+			},
+			wantErr: "migrations create_tables and create_wallet share version 1",
+		},
+		{
+			name:    "zero version",
+			fsys:    fstest.MapFS{"000000_init.up.sql": file("CREATE TABLE")},
+			wantErr: "invalid version of migration 000000_init.up.sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := Load(tt.fsys)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			versions := make([]uint, 0, len(loaded))
+			for _, m := range loaded {
+				versions = append(versions, m.Version)
+			}
+			require.Equal(t, tt.wantVersions, versions)
+		})
+	}
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, m := range loaded {
+		require.Equal(t, uint(i+1), m.Version, "migrations are numbered without gaps")
+	}
+}
+
+// TestMigrator_Up needs a database, it is configured by the PG* environment like the service.
+// It only applies pending migrations, so it is safe against a database in use.
+// Local runs without PGHOST skip it, the integration run sets INTEGRATION and fails instead.
+func TestMigrator_Up(t *testing.T) {
+	requirePostgres(t)
+
+	database, err := db.New(config.Default().DB)
+	require.NoError(t, err)
+	defer database.Close()
+
+	migrator, err := New(database, migrations.FS)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied, "migrations are applied once")
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, migrator.Latest(), status.Version)
+	require.False(t, status.Dirty)
+	require.Empty(t, status.Pending)
+}
+
+// TestMigration_TransactionBackfill runs the migrations before 000009 in a schema of its own,
+// inserts operations like the service did before transactions and checks how they are grouped.
+func TestMigration_TransactionBackfill(t *testing.T) {
+	requirePostgres(t)
+
+	ctx := context.Background()
+	database := newSchema(t)
+
+	_, err := mustNew(t, database, 8).Up(ctx)
+	require.NoError(t, err)
+
+	var a, b, c int64
+	for _, id := range []*int64{&a, &b, &c} {
+		require.NoError(t, database.QueryRowContext(ctx, "INSERT INTO wallet DEFAULT VALUES RETURNING id").Scan(id))
+	}
+
+	transferAt := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	depositAt := transferAt.Add(time.Hour)
+	insert := func(walletID, value int64, direction int, key string, at time.Time) int64 {
+		var id int64
+		err := database.QueryRowContext(ctx, `INSERT INTO operation(wallet_id, value, direction, date, idempotency_key, created_at)
+VALUES ($1, $2, $3, $4::TIMESTAMPTZ::DATE, $5, $4) RETURNING id`, walletID, value, direction, at, key).Scan(&id)
+		require.NoError(t, err)
+		return id
+	}
+	// key k is reused by a deposit on another wallet after the transfer
+	transferWithdrawal := insert(a, 100, 1, "k", transferAt)
+	transferDeposit := insert(b, 100, 0, "k", transferAt)
+	reusedDeposit := insert(c, 100, 0, "k", depositAt)
+	// key r is shared by a deposit and a withdrawal made at different times
+	singleDeposit := insert(a, 50, 0, "r", transferAt)
+	singleWithdrawal := insert(b, 50, 1, "r", depositAt)
+
+	_, err = mustNew(t, database, 9).Up(ctx)
+	require.NoError(t, err)
+
+	type leg struct {
+		TransactionID int64
+		Type          string
+		Counterparty  *int64
+	}
+	legOf := func(operationID int64) leg {
+		var l leg
+		err := database.QueryRowContext(ctx, `SELECT o.transaction_id, t.type, o.counterparty_wallet_id
+FROM operation o JOIN transaction t ON t.id = o.transaction_id WHERE o.id = $1`, operationID).
+			Scan(&l.TransactionID, &l.Type, &l.Counterparty)
+		require.NoError(t, err)
+		return l
+	}
+
+	withdrawal, deposit := legOf(transferWithdrawal), legOf(transferDeposit)
+	require.Equal(t, "transfer", withdrawal.Type)
+	require.Equal(t, withdrawal.TransactionID, deposit.TransactionID, "transfer legs share a transaction")
+	require.Equal(t, &b, withdrawal.Counterparty)
+	require.Equal(t, &a, deposit.Counterparty)
+
+	reused := legOf(reusedDeposit)
+	require.Equal(t, "deposit", reused.Type)
+	require.NotEqual(t, withdrawal.TransactionID, reused.TransactionID)
+	require.Nil(t, reused.Counterparty)
+
+	single, other := legOf(singleDeposit), legOf(singleWithdrawal)
+	require.Equal(t, "deposit", single.Type)
+	require.Equal(t, "withdrawal", other.Type)
+	require.NotEqual(t, single.TransactionID, other.TransactionID)
+	require.Nil(t, single.Counterparty)
+	require.Nil(t, other.Counterparty)
+}
+
+// requirePostgres skips tests which need a database on local runs without PGHOST,
+// the integration run sets INTEGRATION and fails instead.
+func requirePostgres(t *testing.T) {
+	t.Helper()
+	_, hasHost := os.LookupEnv("PGHOST")
+	if _, integration := os.LookupEnv("INTEGRATION"); integration {
+		require.True(t, hasHost, "integration run requires postgres, set PGHOST")
+	} else if testing.Short() || !hasHost {
+		t.Skip("requires postgres, set PGHOST to run")
+	}
+}
+
+// newSchema connects with a single connection whose search_path is an empty schema,
+// the schema is dropped when the test ends.
+func newSchema(t *testing.T) *sqlx.DB {
+	t.Helper()
+	pool := config.Default().DB
+	pool.MaxOpenConns, pool.MaxIdleConns = 1, 1
+	pool.ConnMaxLifetime, pool.ConnMaxIdleTime = 0, 0
+
+	database, err := db.New(pool)
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	schema := "migrate_test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	_, err = database.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	_, err = database.Exec("SET search_path TO " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := database.Exec("DROP SCHEMA " + schema + " CASCADE")
+		require.NoError(t, err)
+	})
+	return database
+}
+
+// mustNew returns a Migrator of the embedded migrations up to version.
+func mustNew(t *testing.T, database *sqlx.DB, version uint) *Migrator {
+	t.Helper()
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(match[1], 10, 64)
+		require.NoError(t, err)
+		if uint(v) > version {
+			continue
+		}
+		data, err := fs.ReadFile(migrations.FS, entry.Name())
+		require.NoError(t, err)
+		fsys[entry.Name()] = &fstest.MapFile{Data: data}
+	}
+
+	migrator, err := New(database, fsys)
+	require.NoError(t, err)
+	return migrator
+}
@@ -0,0 +1,192 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrPrecision and ErrOverflow are invalid amounts too, errors.Is matches them with ErrInvalidAmount.
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrPrecision     = fmt.Errorf("%w: precision exceeds minor units", ErrInvalidAmount)
+	ErrOverflow      = fmt.Errorf("%w: overflows", ErrInvalidAmount)
+)
+
+// maxScale is the biggest power of ten which fits into int64.
+const maxScale = 18
+
+// Amount is an exact decimal amount of money: coefficient * 10^-scale.
+// It is never converted to float, so no cent can be lost or invented.
+type Amount struct {
+	coefficient int64
+	scale       int
+}
+
+func NewAmount(coefficient int64, scale int) Amount {
+	return Amount{coefficient: coefficient, scale: scale}
+}
+
+func ParseAmount(s string) (Amount, error) {
+	digits := s
+	negative := false
+	switch {
+	case strings.HasPrefix(digits, "-"):
+		negative = true
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "+"):
+		digits = digits[1:]
+	}
+
+	integer, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integer, fraction = digits[:i], digits[i+1:]
+		if fraction == "" {
+			return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+	}
+	if integer == "" {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	var coefficient int64
+	for _, r := range integer + fraction {
+		if r < '0' || r > '9' {
+			return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		digit := int64(r - '0')
+		if coefficient > (math.MaxInt64-digit)/10 {
+			return Amount{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+		}
+		coefficient = coefficient*10 + digit
+	}
+
+	if negative {
+		coefficient = -coefficient
+	}
+
+	return Amount{coefficient: coefficient, scale: len(fraction)}, nil
+}
+
+// MinorUnits converts the amount into minor units of a currency with the given exponent,
+// e.g. cents for exponent 2. It fails instead of rounding.
+func (a Amount) MinorUnits(exponent int) (int64, error) {
+	if a.scale > exponent {
+		if a.scale-exponent > maxScale {
+			if a.coefficient == 0 {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("%w: %s", ErrPrecision, a)
+		}
+		divisor := pow10(a.scale - exponent)
+		if a.coefficient%divisor != 0 {
+			return 0, fmt.Errorf("%w: %s", ErrPrecision, a)
+		}
+		return a.coefficient / divisor, nil
+	}
+
+	if exponent-a.scale > maxScale {
+		if a.coefficient == 0 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, a)
+	}
+	multiplier := pow10(exponent - a.scale)
+	if a.coefficient > math.MaxInt64/multiplier || a.coefficient < -math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, a)
+	}
+
+	return a.coefficient * multiplier, nil
+}
+
+func (a Amount) IsZero() bool {
+	return a.coefficient == 0
+}
+
+func (a Amount) Sign() int {
+	switch {
+	case a.coefficient > 0:
+		return 1
+	case a.coefficient < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// Cmp compares amounts of any scale, it returns -1, 0 or +1 like big.Int.Cmp.
+func (a Amount) Cmp(b Amount) int {
+	scale := a.scale
+	if b.scale > scale {
+		scale = b.scale
+	}
+
+	return a.scaled(scale).Cmp(b.scaled(scale))
+}
+
+// scaled returns the coefficient of the same amount with a bigger scale.
+func (a Amount) scaled(scale int) *big.Int {
+	multiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-a.scale)), nil)
+	return multiplier.Mul(multiplier, big.NewInt(a.coefficient))
+}
+
+func (a Amount) String() string {
+	abs := uint64(a.coefficient)
+	sign := ""
+	if a.coefficient < 0 {
+		abs = uint64(-a.coefficient)
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if a.scale <= 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= a.scale {
+		digits = strings.Repeat("0", a.scale-len(digits)+1) + digits
+	}
+	point := len(digits) - a.scale
+
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// MarshalJSON encodes the amount as a string to keep it exact for any client.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts both JSON strings ("100.53") and numbers (100.53).
+// Numbers are parsed from their literal text, never through float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(s, "\"") {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
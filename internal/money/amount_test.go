@@ -0,0 +1,129 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Amount
+		wantErr error
+	}{
+		{name: "integer", s: "100", want: NewAmount(100, 0)},
+		{name: "with cents", s: "0.29", want: NewAmount(29, 2)},
+		{name: "negative", s: "-1.5", want: NewAmount(-15, 1)},
+		{name: "explicit plus", s: "+1.05", want: NewAmount(105, 2)},
+		{name: "empty", s: "", wantErr: ErrInvalidAmount},
+		{name: "only point", s: ".", wantErr: ErrInvalidAmount},
+		{name: "no integer part", s: ".5", wantErr: ErrInvalidAmount},
+		{name: "no fraction part", s: "5.", wantErr: ErrInvalidAmount},
+		{name: "exponent", s: "1e2", wantErr: ErrInvalidAmount},
+		{name: "letters", s: "boo", wantErr: ErrInvalidAmount},
+		{name: "overflow", s: "92233720368547758.08", wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAmount(tt.s)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAmount_MinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		exponent int
+		want     int64
+		wantErr  error
+	}{
+		{name: "float-unfriendly value", amount: "0.29", exponent: 2, want: 29},
+		{name: "whole amount", amount: "100", exponent: 2, want: 10000},
+		{name: "trailing zeros", amount: "1.500", exponent: 2, want: 150},
+		{name: "zero exponent", amount: "150", exponent: 0, want: 150},
+		{name: "three digits exponent", amount: "1.005", exponent: 3, want: 1005},
+		{name: "negative", amount: "-0.01", exponent: 2, want: -1},
+		{name: "sub-cent precision", amount: "1.553", exponent: 2, wantErr: ErrPrecision},
+		{name: "fraction for zero exponent", amount: "1.5", exponent: 0, wantErr: ErrPrecision},
+		{name: "overflow", amount: "92233720368547758.07", exponent: 3, wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := ParseAmount(tt.amount)
+			require.NoError(t, err)
+			got, err := amount.MinorUnits(tt.exponent)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				require.True(t, errors.Is(err, ErrInvalidAmount), "got error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAmount_Cmp(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Amount
+		want int
+	}{
+		{name: "equal with different scales", a: NewAmount(150, 2), b: NewAmount(15, 1), want: 0},
+		{name: "less", a: NewAmount(149, 2), b: NewAmount(15, 1), want: -1},
+		{name: "greater", a: NewAmount(2, 0), b: NewAmount(1999, 3), want: 1},
+		{name: "negative", a: NewAmount(-1, 0), b: NewAmount(0, 2), want: -1},
+		{name: "no overflow", a: NewAmount(math.MaxInt64, 0), b: NewAmount(math.MaxInt64, 18), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.a.Cmp(tt.b))
+		})
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		want   string
+	}{
+		{name: "cents", amount: NewAmount(115, 2), want: "1.15"},
+		{name: "only cents", amount: NewAmount(1, 2), want: "0.01"},
+		{name: "whole", amount: NewAmount(10000, 2), want: "100.00"},
+		{name: "zero exponent", amount: NewAmount(150, 0), want: "150"},
+		{name: "negative", amount: NewAmount(-5, 3), want: "-0.005"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.amount.String())
+		})
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var fromNumber, fromString Amount
+	require.NoError(t, json.Unmarshal([]byte("100.53"), &fromNumber))
+	require.NoError(t, json.Unmarshal([]byte("\"100.53\""), &fromString))
+	require.Equal(t, NewAmount(10053, 2), fromNumber)
+	require.Equal(t, fromNumber, fromString)
+
+	require.Error(t, json.Unmarshal([]byte("1e2"), &fromNumber))
+	require.Error(t, json.Unmarshal([]byte("true"), &fromNumber))
+
+	marshaled, err := json.Marshal(NewAmount(10053, 2))
+	require.NoError(t, err)
+	require.Equal(t, "\"100.53\"", string(marshaled))
+}
@@ -0,0 +1,37 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		want         Currency
+		wantExponent int
+		wantErr      error
+	}{
+		{name: "dollar", code: "USD", want: USD, wantExponent: 2},
+		{name: "lower case", code: "eur", want: "EUR", wantExponent: 2},
+		{name: "yen", code: "JPY", want: "JPY", wantExponent: 0},
+		{name: "dinar", code: "KWD", want: "KWD", wantExponent: 3},
+		{name: "unknown", code: "XXX", wantErr: ErrUnknownCurrency},
+		{name: "empty", code: "", wantErr: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCurrency(tt.code)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantExponent, got.Exponent())
+		})
+	}
+}
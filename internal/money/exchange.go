@@ -0,0 +1,28 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Exchange converts minor units of one currency into minor units of another one by the rate.
+// The result is rounded toward zero, so the conversion never invents money.
+func Exchange(units int64, from, to Currency, rate Amount) (int64, error) {
+	if rate.Sign() <= 0 {
+		return 0, fmt.Errorf("%w: rate %s is not positive", ErrInvalidAmount, rate)
+	}
+
+	result := new(big.Int).Mul(big.NewInt(units), big.NewInt(rate.coefficient))
+	shift := to.Exponent() - from.Exponent() - rate.scale
+	if shift > 0 {
+		result.Mul(result, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shift)), nil))
+	} else if shift < 0 {
+		result.Quo(result, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-shift)), nil))
+	}
+
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("%w: %d %s by rate %s", ErrOverflow, units, from, rate)
+	}
+
+	return result.Int64(), nil
+}
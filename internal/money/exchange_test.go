@@ -0,0 +1,39 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name    string
+		units   int64
+		from    Currency
+		to      Currency
+		rate    Amount
+		want    int64
+		wantErr error
+	}{
+		{name: "same exponent", units: 10000, from: "USD", to: "EUR", rate: NewAmount(92, 2), want: 9200},
+		{name: "rounds toward zero", units: 1, from: "USD", to: "EUR", rate: NewAmount(92, 2), want: 0},
+		{name: "to zero exponent", units: 150, from: "USD", to: "JPY", rate: NewAmount(14512, 2), want: 217},
+		{name: "from zero exponent", units: 1000, from: "JPY", to: "USD", rate: NewAmount(69, 4), want: 690},
+		{name: "to three digits exponent", units: 100, from: "USD", to: "KWD", rate: NewAmount(307, 3), want: 307},
+		{name: "non positive rate", units: 100, from: "USD", to: "EUR", rate: NewAmount(0, 0), wantErr: ErrInvalidAmount},
+		{name: "overflow", units: 1 << 62, from: "USD", to: "JPY", rate: NewAmount(1000, 0), wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Exchange(tt.units, tt.from, tt.to, tt.rate)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+//go:generate mockgen -source=reconciliation.go -destination mock.go -package $GOPACKAGE
+package reconciliation
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"payment-system/internal/logging"
+	"payment-system/internal/money"
+	"payment-system/internal/storage"
+)
+
+var ErrReasonRequired = errors.New("reason of adjustments is required")
+
+// initiator is recorded on adjustment transactions.
+const initiator = "reconciliation"
+
+// Drift is a wallet whose balance differs from the balance recomputed from its operations or ledger postings.
+// AdjustmentTransactionID is set when corrective entries were recorded.
+type Drift struct {
+	WalletID                int64          `json:"wallet_id"`
+	Currency                money.Currency `json:"currency"`
+	WalletBalance           money.Amount   `json:"wallet_balance"`
+	OperationsBalance       money.Amount   `json:"operations_balance"`
+	LedgerBalance           money.Amount   `json:"ledger_balance"`
+	AdjustmentTransactionID int64          `json:"adjustment_transaction_id,omitempty"`
+}
+
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Drifts      []Drift   `json:"drifts"`
+}
+
+type reconciliationStorage interface {
+	GetBalanceDrifts(ctx context.Context) ([]storage.BalanceDrift, error)
+	AdjustBalance(ctx context.Context, info storage.Adjustment) (storage.BalanceDrift, int64, error)
+}
+
+type Reconciler struct {
+	storage reconciliationStorage
+	now     func() time.Time
+}
+
+func New(storage reconciliationStorage) *Reconciler {
+	return &Reconciler{storage: storage, now: time.Now}
+}
+
+// Check recomputes balances of all wallets and reports the ones which drifted.
+func (r *Reconciler) Check(ctx context.Context) (Report, error) {
+	drifts, err := r.storage.GetBalanceDrifts(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("getting balance drifts from storage: %w", err)
+	}
+
+	report := Report{GeneratedAt: r.now(), Drifts: make([]Drift, 0, len(drifts))}
+	for _, drift := range drifts {
+		report.Drifts = append(report.Drifts, toDrift(drift))
+	}
+
+	return report, nil
+}
+
+// Fix records adjustment entries under the audit reason for every drifted wallet,
+// so its operations and ledger sum up to the wallet balance again. The wallet balance is not changed.
+// Drifts are recomputed under a lock of the wallet, the report holds the ones which were adjusted.
+func (r *Reconciler) Fix(ctx context.Context, reason string) (Report, error) {
+	if reason == "" {
+		return Report{}, ErrReasonRequired
+	}
+
+	report, err := r.Check(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	adjusted := make([]Drift, 0, len(report.Drifts))
+	for _, d := range report.Drifts {
+		adjustment := storage.Adjustment{
+			WalletID:       d.WalletID,
+			Reason:         reason,
+			Initiator:      initiator,
+			IdempotencyKey: uuid.New().String(),
+		}
+		drift, transactionID, err := r.storage.AdjustBalance(ctx, adjustment)
+		if err != nil {
+			return Report{}, fmt.Errorf("adjusting balance of wallet %d: %w", d.WalletID, err)
+		}
+
+		// the drift could have been fixed concurrently
+		if transactionID == 0 {
+			continue
+		}
+
+		fixed := toDrift(drift)
+		fixed.AdjustmentTransactionID = transactionID
+		adjusted = append(adjusted, fixed)
+	}
+	report.Drifts = adjusted
+
+	return report, nil
+}
+
+// Run checks balances every interval until the context is done, drifts are reported to the log.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := r.Check(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to reconcile balances", "error", err)
+			continue
+		}
+
+		for _, drift := range report.Drifts {
+			logging.FromContext(ctx).Warn("balance drift", "wallet_id", drift.WalletID,
+				"wallet_balance", logging.Amount(drift.WalletBalance),
+				"operations_balance", logging.Amount(drift.OperationsBalance),
+				"ledger_balance", logging.Amount(drift.LedgerBalance),
+				"currency", drift.Currency)
+		}
+	}
+}
+
+func WriteJSON(w io.Writer, report Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func WriteCSV(w io.Writer, report Report) error {
+	records := make([][]string, 0, len(report.Drifts)+1)
+	records = append(records, []string{"wallet_id", "currency", "wallet_balance", "operations_balance",
+		"ledger_balance", "adjustment_transaction_id"})
+	for _, drift := range report.Drifts {
+		adjustmentTransactionID := ""
+		if drift.AdjustmentTransactionID != 0 {
+			adjustmentTransactionID = strconv.FormatInt(drift.AdjustmentTransactionID, 10)
+		}
+		record := []string{
+			strconv.FormatInt(drift.WalletID, 10),
+			string(drift.Currency),
+			drift.WalletBalance.String(),
+			drift.OperationsBalance.String(),
+			drift.LedgerBalance.String(),
+			adjustmentTransactionID,
+		}
+		records = append(records, record)
+	}
+
+	return csv.NewWriter(w).WriteAll(records)
+}
+
+func toDrift(drift storage.BalanceDrift) Drift {
+	currency := money.Currency(drift.Currency)
+	return Drift{
+		WalletID:          drift.WalletID,
+		Currency:          currency,
+		WalletBalance:     money.NewAmount(drift.WalletBalance, currency.Exponent()),
+		OperationsBalance: money.NewAmount(drift.OperationsBalance, currency.Exponent()),
+		LedgerBalance:     money.NewAmount(drift.LedgerBalance, currency.Exponent()),
+	}
+}
@@ -0,0 +1,77 @@
+package reconciliation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/money"
+	"payment-system/internal/storage"
+)
+
+func TestReconciler_Check(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockreconciliationStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockStorage.EXPECT().GetBalanceDrifts(gomock.Any()).Return([]storage.BalanceDrift{
+		{WalletID: 1, Currency: "USD", WalletBalance: 1053, OperationsBalance: 1000, LedgerBalance: 1053},
+	}, nil)
+	reconciler := New(mockStorage)
+	reconciler.now = func() time.Time { return now }
+	report, err := reconciler.Check(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Report{
+		GeneratedAt: now,
+		Drifts: []Drift{{
+			WalletID:          1,
+			Currency:          money.USD,
+			WalletBalance:     money.NewAmount(1053, 2),
+			OperationsBalance: money.NewAmount(1000, 2),
+			LedgerBalance:     money.NewAmount(1053, 2),
+		}},
+	}, report)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteCSV(&out, report))
+	require.Equal(t, "wallet_id,currency,wallet_balance,operations_balance,ledger_balance,adjustment_transaction_id\n"+
+		"1,USD,10.53,10.00,10.53,\n", out.String())
+}
+
+func TestReconciler_Fix_RequiresReason(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockreconciliationStorage(ctrl)
+	reconciler := New(mockStorage)
+	_, err := reconciler.Fix(context.Background(), "")
+	require.True(t, errors.Is(err, ErrReasonRequired))
+}
+
+func TestReconciler_Fix_AdjustsDriftedWallets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := NewMockreconciliationStorage(ctrl)
+	mockStorage.EXPECT().GetBalanceDrifts(gomock.Any()).Return([]storage.BalanceDrift{
+		{WalletID: 1, Currency: "USD", WalletBalance: 1053, OperationsBalance: 1000, LedgerBalance: 1053},
+		{WalletID: 2, Currency: "JPY", WalletBalance: 100, OperationsBalance: 100, LedgerBalance: 0},
+	}, nil)
+	mockStorage.EXPECT().AdjustBalance(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, info storage.Adjustment) (storage.BalanceDrift, int64, error) {
+			require.Equal(t, int64(1), info.WalletID)
+			require.Equal(t, "manual update of wallet", info.Reason)
+			require.Equal(t, "reconciliation", info.Initiator)
+			require.NotEmpty(t, info.IdempotencyKey)
+			return storage.BalanceDrift{WalletID: 1, Currency: "USD", WalletBalance: 1053, OperationsBalance: 1000, LedgerBalance: 1053}, 7, nil
+		})
+	// the second wallet was fixed concurrently
+	mockStorage.EXPECT().AdjustBalance(gomock.Any(), gomock.Any()).Return(
+		storage.BalanceDrift{WalletID: 2, Currency: "JPY", WalletBalance: 100, OperationsBalance: 100, LedgerBalance: 100}, int64(0), nil)
+	reconciler := New(mockStorage)
+	report, err := reconciler.Fix(context.Background(), "manual update of wallet")
+	require.NoError(t, err)
+	require.Len(t, report.Drifts, 1)
+	require.Equal(t, int64(1), report.Drifts[0].WalletID)
+	require.Equal(t, int64(7), report.Drifts[0].AdjustmentTransactionID)
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/logging"
+)
+
+// Field maps a path or query parameter to a field of the JSON body the handlers of the service read.
+type Field struct {
+	param  string
+	name   string
+	number bool
+}
+
+// IntField maps the parameter to a number field, e.g. the wallet id of /v1/wallets/{id}.
+func IntField(param, name string) Field {
+	return Field{param: param, name: name, number: true}
+}
+
+func StringField(param, name string) Field {
+	return Field{param: param, name: name}
+}
+
+// WithFields adds the path parameters and query parameters of the fields to the JSON body of the request,
+// so a resource route is served by the handler of the legacy endpoint. The idempotency fingerprint
+// of the body then covers the path too. A field set in both the body and the path must be the same.
+// The fields are added to the access log entry of the request as well.
+func WithFields(next http.Handler, fields ...Field) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httperror.WriteBadRequest(w, err)
+			return
+		}
+
+		payload := make(map[string]json.RawMessage)
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := json.Unmarshal(body, &payload); err != nil {
+				httperror.WriteBadRequest(w, fmt.Errorf("body is not a JSON object: %w", err))
+				return
+			}
+		}
+
+		query := r.URL.Query()
+		for _, field := range fields {
+			value := Param(r, field.param)
+			if value == "" {
+				value = query.Get(field.param)
+			}
+			if value == "" {
+				continue
+			}
+
+			encoded, err := field.encode(value)
+			if err != nil {
+				httperror.WriteBadRequest(w, err)
+				return
+			}
+			if existing, ok := payload[field.name]; ok && !bytes.Equal(bytes.TrimSpace(existing), encoded) {
+				httperror.WriteBadRequest(w, fmt.Errorf("%s of the body differs from the %s parameter", field.name, field.param))
+				return
+			}
+			payload[field.name] = encoded
+			logging.SetRequestField(r.Context(), field.name, field.logValue(encoded))
+		}
+
+		merged, err := json.Marshal(payload)
+		if err != nil {
+			httperror.WriteBadRequest(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(merged))
+		r.ContentLength = int64(len(merged))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logValue is the encoded value like the access log reads it from a JSON body.
+func (f Field) logValue(encoded json.RawMessage) interface{} {
+	if f.number {
+		return json.Number(encoded)
+	}
+
+	var value string
+	_ = json.Unmarshal(encoded, &value)
+	return value
+}
+
+func (f Field) encode(value string) (json.RawMessage, error) {
+	if !f.number {
+		return json.Marshal(value)
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a number: %q", f.param, value)
+	}
+
+	return json.RawMessage(strconv.FormatInt(number, 10)), nil
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/logging"
+)
+
+func TestWithFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "path param into body",
+			method:     http.MethodPost,
+			path:       "/v1/wallets/1/deposits",
+			body:       `{"value": "10.50", "idempotency_key": "foo"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"idempotency_key": "foo", "value": "10.50", "wallet_id": 1}`,
+		},
+		{
+			name:       "same value in body and path",
+			method:     http.MethodPost,
+			path:       "/v1/wallets/1/deposits",
+			body:       `{"wallet_id": 1}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"wallet_id": 1}`,
+		},
+		{
+			name:       "query params without body",
+			method:     http.MethodGet,
+			path:       "/v1/wallets/1/operations?from=2024-01-01&direction=0&cursor=abc",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"wallet_id": 1, "from": "2024-01-01", "direction": 0, "cursor": "abc"}`,
+		},
+		{
+			name:       "other value in body",
+			method:     http.MethodPost,
+			path:       "/v1/wallets/1/deposits",
+			body:       `{"wallet_id": 2}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not a number",
+			method:     http.MethodGet,
+			path:       "/v1/wallets/1/operations?limit=ten",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "body is not an object",
+			method:     http.MethodPost,
+			path:       "/v1/wallets/1/deposits",
+			body:       `[1]`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := WithFields(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				got = string(body)
+			}),
+				IntField("id", "wallet_id"),
+				StringField("from", "from"),
+				IntField("direction", "direction"),
+				IntField("limit", "limit"),
+				StringField("cursor", "cursor"),
+			)
+			rt := New()
+			rt.Handle(tt.method, "/v1/wallets/{id}/deposits", handler)
+			rt.Handle(tt.method, "/v1/wallets/{id}/operations", handler)
+
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			if tt.wantBody != "" {
+				require.JSONEq(t, tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestWithFields_LogsPathParams(t *testing.T) {
+	var out bytes.Buffer
+	rt := New()
+	rt.Handle(http.MethodPost, "/v1/holds/{id}/capture", WithFields(http.NotFoundHandler(), IntField("id", "hold_id")))
+	handler := logging.AccessLog(logging.New(&out, logging.Redaction{}), rt)
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/holds/7/capture", strings.NewReader(`{"idempotency_key": "foo"}`))
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	require.Equal(t, "request", entry["msg"])
+	require.Equal(t, float64(7), entry["hold_id"])
+	require.Equal(t, "foo", entry["idempotency_key"])
+}
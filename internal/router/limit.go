@@ -0,0 +1,30 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"payment-system/internal/handlers/httperror"
+)
+
+// LimitBody answers with 413 on a request whose body is longer than limit bytes. The body is read once
+// here, so the middlewares and handlers behind it may buffer it again without unbounded memory.
+func LimitBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+		if err != nil && int64(len(body)) >= limit {
+			httperror.Write(w, http.StatusRequestEntityTooLarge, httperror.CodeRequestTooLarge,
+				fmt.Sprintf("request body is longer than %d bytes", limit))
+			return
+		}
+		if err != nil {
+			httperror.WriteBadRequest(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
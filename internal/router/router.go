@@ -0,0 +1,96 @@
+// Package router routes requests by method and a path pattern like /v1/wallets/{id}/operations,
+// which http.ServeMux of the supported Go version can not do.
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"payment-system/internal/handlers/httperror"
+)
+
+type route struct {
+	method   string
+	segments []string
+	handler  http.Handler
+}
+
+type Router struct {
+	routes []route
+}
+
+func New() *Router {
+	return &Router{}
+}
+
+// Handle routes requests with the method whose path matches the pattern. A pattern segment like {id}
+// matches any segment, its value is returned by Param.
+func (rt *Router) Handle(method, pattern string, handler http.Handler) {
+	rt.routes = append(rt.routes, route{method: method, segments: split(pattern), handler: handler})
+}
+
+// ServeHTTP answers 404 when no pattern matches the path and 405 when no route of the path has the method.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := split(r.URL.Path)
+	var allowed []string
+	for _, route := range rt.routes {
+		params, ok := match(route.segments, segments)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method {
+			allowed = append(allowed, route.method)
+			continue
+		}
+
+		route.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), paramsKey{}, params)))
+		return
+	}
+
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		httperror.Write(w, http.StatusMethodNotAllowed, httperror.CodeMethodNotAllowed,
+			fmt.Sprintf("method %s is not allowed for %s", r.Method, r.URL.Path))
+		return
+	}
+
+	httperror.Write(w, http.StatusNotFound, httperror.CodeNotFound, fmt.Sprintf("%s is not found", r.URL.Path))
+}
+
+type paramsKey struct{}
+
+// Param returns the value of the path segment matched by {name}, empty when there is none.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func match(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[p[1:len(p)-1]] = segments[i]
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/handlers/httperror"
+)
+
+func TestRouter(t *testing.T) {
+	rt := New()
+	for _, r := range []struct{ method, pattern string }{
+		{http.MethodPost, "/v1/wallets"},
+		{http.MethodGet, "/v1/wallets/{id}"},
+		{http.MethodGet, "/v1/wallets/{id}/operations"},
+		{http.MethodPost, "/v1/holds/{id}/capture"},
+	} {
+		pattern := r.pattern
+		rt.Handle(r.method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]string{"pattern": pattern, "id": Param(r, "id")})
+		}))
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantPattern string
+		wantID      string
+		wantCode    string
+		wantAllow   string
+	}{
+		{name: "static", method: http.MethodPost, path: "/v1/wallets", wantStatus: http.StatusOK, wantPattern: "/v1/wallets"},
+		{name: "param", method: http.MethodGet, path: "/v1/wallets/42", wantStatus: http.StatusOK, wantPattern: "/v1/wallets/{id}", wantID: "42"},
+		{name: "nested", method: http.MethodGet, path: "/v1/wallets/42/operations", wantStatus: http.StatusOK, wantPattern: "/v1/wallets/{id}/operations", wantID: "42"},
+		{name: "trailing slash", method: http.MethodPost, path: "/v1/holds/7/capture/", wantStatus: http.StatusOK, wantPattern: "/v1/holds/{id}/capture", wantID: "7"},
+		{name: "other method", method: http.MethodDelete, path: "/v1/wallets", wantStatus: http.StatusMethodNotAllowed, wantCode: httperror.CodeMethodNotAllowed, wantAllow: "POST"},
+		{name: "unknown path", method: http.MethodGet, path: "/v1/wallets/42/holds", wantStatus: http.StatusNotFound, wantCode: httperror.CodeNotFound},
+		{name: "empty param", method: http.MethodGet, path: "/v1/wallets//operations", wantStatus: http.StatusNotFound, wantCode: httperror.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			require.Equal(t, tt.wantAllow, recorder.Header().Get("Allow"))
+
+			if tt.wantCode != "" {
+				var errorDTO httperror.ErrorDTO
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &errorDTO))
+				require.Equal(t, tt.wantCode, errorDTO.Code)
+				return
+			}
+
+			var got map[string]string
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+			require.Equal(t, tt.wantPattern, got["pattern"])
+			require.Equal(t, tt.wantID, got["id"])
+		})
+	}
+}
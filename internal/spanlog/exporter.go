@@ -0,0 +1,24 @@
+package spanlog
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// WriterExporter writes every span as a JSON line, e.g. to stdout or a file for local use.
+type WriterExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{encoder: json.NewEncoder(w)}
+}
+
+func (e *WriterExporter) Export(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_ = e.encoder.Encode(span)
+}
@@ -0,0 +1,44 @@
+package spanlog
+
+import (
+	"net/http"
+
+	"payment-system/internal/logging"
+)
+
+// Middleware starts a span of every request. The parent is taken from the traceparent header, the trace id
+// is added to the request logger, so logs and spans of a request are found by each other.
+func Middleware(tracer *Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewContext(r.Context(), tracer)
+		if sc, err := ParseTraceparent(r.Header.Get(TraceparentHeader)); err == nil {
+			ctx = ContextWithRemote(ctx, sc)
+		}
+
+		ctx, span := Start(ctx, "HTTP "+r.Method+" "+r.URL.Path,
+			"http.method", r.Method,
+			"http.target", r.URL.Path,
+		)
+		if requestID := logging.RequestID(ctx); requestID != "" {
+			span.SetAttributes("request_id", requestID)
+		}
+		ctx = logging.NewContext(ctx, logging.FromContext(ctx).With("trace_id", span.SpanContext().TraceID.String()))
+		w.Header().Set(TraceparentHeader, FormatTraceparent(span.SpanContext()))
+
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes("http.status_code", recorder.statusCode)
+		span.End(nil)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
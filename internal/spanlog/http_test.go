@@ -0,0 +1,49 @@
+package spanlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/logging"
+)
+
+func TestMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	exporter := NewWriterExporter(&out)
+	var logs bytes.Buffer
+	logger := logging.New(&logs, logging.Redaction{})
+
+	handler := logging.AccessLog(logger, Middleware(New(exporter), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "wallet.DepositMoney")
+		span.End(nil)
+
+		logging.FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusConflict)
+	})))
+
+	request := httptest.NewRequest(http.MethodPost, "/depositMoney", nil)
+	request.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	request.Header.Set(logging.RequestIDHeader, "abc")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	decoder := json.NewDecoder(&out)
+	var child, server SpanData
+	require.NoError(t, decoder.Decode(&child))
+	require.NoError(t, decoder.Decode(&server))
+
+	require.Equal(t, "HTTP POST /depositMoney", server.Name)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", server.ParentSpanID)
+	require.Equal(t, "abc", server.Attributes["request_id"])
+	require.Equal(t, float64(http.StatusConflict), server.Attributes["http.status_code"])
+	require.Equal(t, server.SpanID, child.ParentSpanID)
+
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+server.SpanID+"-01", recorder.Header().Get(TraceparentHeader))
+	require.Contains(t, logs.String(), `"msg":"handled","request_id":"abc","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+}
@@ -0,0 +1,58 @@
+package spanlog
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TraceparentHeader carries the trace context as defined by W3C Trace Context:
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+const TraceparentHeader = "traceparent"
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent accepts version 00 and, as the specification requires, prefixes of later versions.
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("%w: %q", errInvalidTraceparent, value)
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	for _, field := range []struct {
+		hex string
+		dst []byte
+	}{
+		{hex: parts[0], dst: make([]byte, 1)},
+		{hex: parts[1], dst: sc.TraceID[:]},
+		{hex: parts[2], dst: sc.SpanID[:]},
+		{hex: parts[3], dst: flags[:]},
+	} {
+		if len(field.hex) != 2*len(field.dst) || strings.ToLower(field.hex) != field.hex {
+			return SpanContext{}, fmt.Errorf("%w: %q", errInvalidTraceparent, value)
+		}
+		if _, err := hex.Decode(field.dst, []byte(field.hex)); err != nil {
+			return SpanContext{}, fmt.Errorf("%w: %q", errInvalidTraceparent, value)
+		}
+	}
+
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("%w: zero id in %q", errInvalidTraceparent, value)
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, nil
+}
+
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
@@ -0,0 +1,214 @@
+// Package spanlog records spans of requests through handlers, the wallet service and SQL statements
+// and writes them as JSON lines for local use. It is a small recorder of its own, not OpenTelemetry:
+// there is no OpenTelemetry API, SDK or OTLP export. Only the W3C traceparent header is shared with it,
+// so spans keep the trace id of a caller. The tracer travels in the context, without it spans are not recorded.
+package spanlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span within its trace, it is what crosses process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanData is a finished span as exporters receive it.
+type SpanData struct {
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Name         string                 `json:"name"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	DurationMS   float64                `json:"duration_ms"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+type Exporter interface {
+	Export(span SpanData)
+}
+
+type Tracer struct {
+	exporter Exporter
+	now      func() time.Time
+}
+
+func New(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter, now: time.Now}
+}
+
+// Span is safe to use when nil, so code does not check whether tracing is enabled.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	name   string
+	start  time.Time
+
+	mu         sync.Mutex
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.sc
+}
+
+// SetAttributes adds key value pairs to the span.
+func (s *Span) SetAttributes(keyvals ...interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		s.attributes[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+}
+
+// End finishes the span. The error is read at the end, so `defer span.End(&err)` records the error
+// a function returns. Pass nil when the span can not fail.
+func (s *Span) End(err *error) {
+	if s == nil {
+		return
+	}
+
+	end := s.tracer.now()
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		TraceID:    s.sc.TraceID.String(),
+		SpanID:     s.sc.SpanID.String(),
+		Name:       s.name,
+		Start:      s.start,
+		End:        end,
+		DurationMS: float64(end.Sub(s.start).Microseconds()) / 1000,
+		Attributes: make(map[string]interface{}, len(s.attributes)),
+	}
+	for key, value := range s.attributes {
+		data.Attributes[key] = value
+	}
+	s.mu.Unlock()
+
+	if s.parent.IsValid() {
+		data.ParentSpanID = s.parent.String()
+	}
+	if err != nil && *err != nil {
+		data.Error = (*err).Error()
+	}
+
+	s.tracer.exporter.Export(data)
+}
+
+type tracerKey struct{}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+func NewContext(ctx context.Context, tracer *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// SpanFromContext returns the current span of the context, nil when there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemote makes the span of another process the parent of spans started with the context.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Start starts a child of the current span, or a root span when the context has none.
+func Start(ctx context.Context, name string, keyvals ...interface{}) (context.Context, *Span) {
+	tracer, _ := ctx.Value(tracerKey{}).(*Tracer)
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:     tracer,
+		name:       name,
+		start:      tracer.now(),
+		attributes: make(map[string]interface{}),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.sc.TraceID, span.parent = parent.sc.TraceID, parent.sc.SpanID
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok && remote.IsValid() {
+		span.sc.TraceID, span.parent = remote.TraceID, remote.SpanID
+	} else {
+		span.sc.TraceID = newTraceID()
+	}
+	span.sc.SpanID = newSpanID()
+	span.sc.Sampled = true
+	span.SetAttributes(keyvals...)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
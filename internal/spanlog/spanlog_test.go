@@ -0,0 +1,68 @@
+package spanlog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *recorder) Export(span SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, span)
+}
+
+func TestStart_RecordsChildrenInTheTraceOfTheParent(t *testing.T) {
+	exporter := &recorder{}
+	ctx := NewContext(context.Background(), New(exporter))
+
+	ctx, parent := Start(ctx, "parent", "wallet_id", 1)
+	_, child := Start(ctx, "child")
+	err := errors.New("insufficient funds")
+	child.End(&err)
+	parent.End(nil)
+	parent.End(nil)
+
+	require.Len(t, exporter.spans, 2)
+	childData, parentData := exporter.spans[0], exporter.spans[1]
+	require.Equal(t, "child", childData.Name)
+	require.Equal(t, parentData.TraceID, childData.TraceID)
+	require.Equal(t, parentData.SpanID, childData.ParentSpanID)
+	require.Equal(t, "insufficient funds", childData.Error)
+	require.Empty(t, parentData.ParentSpanID)
+	require.Empty(t, parentData.Error)
+	require.Equal(t, map[string]interface{}{"wallet_id": 1}, parentData.Attributes)
+}
+
+func TestStart_ContinuesRemoteTrace(t *testing.T) {
+	exporter := &recorder{}
+	remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	ctx := ContextWithRemote(NewContext(context.Background(), New(exporter)), remote)
+
+	_, span := Start(ctx, "handler")
+	span.End(nil)
+
+	require.Len(t, exporter.spans, 1)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", exporter.spans[0].TraceID)
+	require.Equal(t, "00f067aa0ba902b7", exporter.spans[0].ParentSpanID)
+}
+
+func TestStart_WithoutTracer(t *testing.T) {
+	ctx, span := Start(context.Background(), "untraced")
+	require.Nil(t, span)
+	require.Nil(t, SpanFromContext(ctx))
+
+	span.SetAttributes("key", "value")
+	span.End(nil)
+	require.False(t, span.SpanContext().IsValid())
+}
@@ -0,0 +1,111 @@
+package spanlog
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// Connector opens connections of the driver whose statements and transactions are recorded as spans
+// of the context they run with. Arguments are not recorded, they may hold amounts and keys.
+func Connector(d driver.Driver, name string) driver.Connector {
+	return &connector{driver: d, name: name}
+}
+
+type connector struct {
+	driver driver.Driver
+	name   string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.driver.Open(c.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: dc}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+type conn struct {
+	driver.Conn
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+
+	return c.Conn.Prepare(query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	spanCtx, span := Start(ctx, "sql.begin")
+	defer span.End(&err)
+
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(spanCtx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{Tx: tx, ctx: ctx}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	_, span := Start(ctx, "sql.exec", "db.statement", query)
+	defer span.End(&err)
+
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	_, span := Start(ctx, "sql.query", "db.statement", query)
+	defer span.End(&err)
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// transaction records commit and rollback in the context the transaction was begun with,
+// database/sql does not pass a context to them.
+type transaction struct {
+	driver.Tx
+	ctx context.Context
+}
+
+func (t *transaction) Commit() (err error) {
+	_, span := Start(t.ctx, "sql.commit")
+	defer span.End(&err)
+
+	return t.Tx.Commit()
+}
+
+func (t *transaction) Rollback() (err error) {
+	_, span := Start(t.ctx, "sql.rollback")
+	defer span.End(&err)
+
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,12 @@
+package storage_test
+
+import (
+	"testing"
+
+	"payment-system/internal/storage/storagetest"
+)
+
+// TestConformance runs the storage suite against the migrated database, see newPostgresStorage.
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, newPostgresStorage(t))
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// openTxs counts open transactions, so the database is closed only after they are committed or rolled back.
+type openTxs struct {
+	mu    sync.Mutex
+	count int
+	// idle is closed when the last open transaction ends
+	idle chan struct{}
+}
+
+func (o *openTxs) add() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.count == 0 {
+		o.idle = make(chan struct{})
+	}
+	o.count++
+}
+
+func (o *openTxs) done() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.count--
+	if o.count == 0 {
+		close(o.idle)
+	}
+}
+
+func (o *openTxs) wait(ctx context.Context) error {
+	o.mu.Lock()
+	if o.count == 0 {
+		o.mu.Unlock()
+		return nil
+	}
+	idle := o.idle
+	o.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until every open transaction ended or the context is done. It is called on shutdown
+// after requests stopped, so no transaction is cut off by closing the database.
+func (s *Storage) Wait(ctx context.Context) error {
+	return s.txs.wait(ctx)
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_openTxs(t *testing.T) {
+	var txs openTxs
+	require.NoError(t, txs.wait(context.Background()), "nothing to wait without transactions")
+
+	txs.add()
+	txs.add()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.True(t, errors.Is(txs.wait(ctx), context.DeadlineExceeded))
+
+	waited := make(chan error)
+	go func() {
+		waited <- txs.wait(context.Background())
+	}()
+	txs.done()
+	select {
+	case <-waited:
+		t.Fatal("wait returned with an open transaction")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	txs.done()
+	require.NoError(t, <-waited)
+
+	txs.add()
+	txs.done()
+	require.NoError(t, txs.wait(context.Background()))
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx"
+)
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+	checkViolationCode      = "23514"
+
+	walletForeignKey             = "fk_wallet"
+	counterpartyWalletForeignKey = "fk_counterparty_wallet"
+	holdFromWalletForeignKey     = "fk_hold_from_wallet"
+	holdToWalletForeignKey       = "fk_hold_to_wallet"
+	valueNonNegativeCheck        = "value_non_negative"
+	heldWithinValueCheck         = "held_within_value"
+)
+
+var walletForeignKeys = map[string]bool{
+	walletForeignKey:             true,
+	counterpartyWalletForeignKey: true,
+	holdFromWalletForeignKey:     true,
+	holdToWalletForeignKey:       true,
+}
+
+var (
+	// ErrDuplicate is returned when an entity with the same idempotency key already exists.
+	ErrDuplicate      = errors.New("duplicate")
+	ErrWalletNotFound = errors.New("wallet not found")
+	ErrQuoteNotFound  = errors.New("quote not found")
+	// ErrQuoteUsed is returned when a transfer uses a locked quote which another transfer already used.
+	ErrQuoteUsed           = errors.New("quote already used")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrHoldNotFound        = errors.New("hold not found")
+	// ErrHoldNotAuthorized is returned on capture or void of a hold which was already captured, voided or expired.
+	ErrHoldNotAuthorized = errors.New("hold is not authorized")
+	// ErrCaptureExceedsHold is returned on capture of more than the hold reserves.
+	ErrCaptureExceedsHold = errors.New("capture exceeds hold")
+	// ErrNotRefundable is returned on refund of a transaction which did not pay from one wallet to another.
+	ErrNotRefundable = errors.New("transaction is not refundable")
+	// ErrRefundExceedsTransaction is returned when refunds together would return more than the transaction paid.
+	ErrRefundExceedsTransaction = errors.New("refund exceeds transaction")
+	// ErrConcurrentUpdate is returned when a storage transaction kept conflicting with concurrent ones
+	// after all retries, the request can be repeated.
+	ErrConcurrentUpdate = errors.New("concurrent update")
+	// ErrInsufficientFunds is returned when a debit would make the wallet balance negative
+	// or would spend funds reserved by holds.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// translateError turns postgres constraint violations into sentinel errors,
+// other errors are returned as is.
+func translateError(err error) error {
+	var pgErr pgx.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch {
+	case pgErr.Code == uniqueViolationCode:
+		return fmt.Errorf("%w: %s", ErrDuplicate, pgErr.ConstraintName)
+	case pgErr.Code == foreignKeyViolationCode && walletForeignKeys[pgErr.ConstraintName]:
+		return fmt.Errorf("%w: %s", ErrWalletNotFound, pgErr.Detail)
+	case pgErr.Code == checkViolationCode &&
+		(pgErr.ConstraintName == valueNonNegativeCheck || pgErr.ConstraintName == heldWithinValueCheck):
+		return ErrInsufficientFunds
+	default:
+		return err
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_translateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "duplicate idempotency key",
+			err:  pgx.PgError{Code: uniqueViolationCode, ConstraintName: "wallet_idempotency_key_unique_idx"},
+			want: ErrDuplicate,
+		},
+		{
+			name: "unknown wallet",
+			err:  pgx.PgError{Code: foreignKeyViolationCode, ConstraintName: walletForeignKey},
+			want: ErrWalletNotFound,
+		},
+		{
+			name: "unknown counterparty wallet",
+			err:  pgx.PgError{Code: foreignKeyViolationCode, ConstraintName: counterpartyWalletForeignKey},
+			want: ErrWalletNotFound,
+		},
+		{
+			name: "negative balance",
+			err:  fmt.Errorf("wrapped: %w", pgx.PgError{Code: checkViolationCode, ConstraintName: valueNonNegativeCheck}),
+			want: ErrInsufficientFunds,
+		},
+		{
+			name: "debit of held funds",
+			err:  pgx.PgError{Code: checkViolationCode, ConstraintName: heldWithinValueCheck},
+			want: ErrInsufficientFunds,
+		},
+		{
+			name: "other check",
+			err:  pgx.PgError{Code: checkViolationCode, ConstraintName: "rate_positive"},
+			want: pgx.PgError{Code: checkViolationCode, ConstraintName: "rate_positive"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.True(t, errors.Is(translateError(tt.err), tt.want))
+		})
+	}
+}
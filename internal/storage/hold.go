@@ -0,0 +1,346 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"payment-system/internal/logging"
+)
+
+const (
+	insertHoldQuery = "INSERT INTO hold(transaction_id, from_wallet_id, to_wallet_id, value, currency, status, " +
+		"idempotency_key, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) " +
+		"RETURNING id, transaction_id, from_wallet_id, to_wallet_id, value, captured_value, currency, status, " +
+		"idempotency_key, expires_at"
+	selectHoldQuery = "SELECT id, transaction_id, from_wallet_id, to_wallet_id, value, captured_value, currency, " +
+		"status, idempotency_key, expires_at FROM hold WHERE id = $1"
+	selectHoldForUpdateQuery = selectHoldQuery + " FOR UPDATE"
+	updateHoldQuery          = "UPDATE hold SET status = $2, captured_value = $3, updated_at = now() WHERE id = $1"
+	updateHeldQuery          = "UPDATE wallet SET held = held + $2, updated_at = now() WHERE id = $1"
+	captureHeldQuery         = "UPDATE wallet SET value = value - $2, held = held - $3, updated_at = now() WHERE id = $1"
+	updateTransactionQuery   = "UPDATE transaction SET status = $2 WHERE id = $1"
+	// stale holds of all wallets are released in a single statement
+	expireHoldsQuery = "WITH expired AS (" +
+		"UPDATE hold SET status = $2, updated_at = now() WHERE status = $3 AND expires_at <= $1 " +
+		"RETURNING transaction_id, from_wallet_id, value), " +
+		"released AS (UPDATE wallet w SET held = w.held - e.value, updated_at = now() " +
+		"FROM (SELECT from_wallet_id, sum(value) AS value FROM expired GROUP BY from_wallet_id) e " +
+		"WHERE w.id = e.from_wallet_id), " +
+		"closed AS (UPDATE transaction SET status = $2 WHERE id IN (SELECT transaction_id FROM expired)) " +
+		"SELECT count(*) FROM expired"
+)
+
+// captureNamespace derives idempotency keys of capture legs from hold ids.
+var captureNamespace = uuid.MustParse("6f1d7c4e-3b8a-4f5e-9c2d-8a7b6e5f4d3c")
+
+// CaptureKey is the idempotency key of the operations of the hold capture. The key of the authorization
+// is only unique among holds of the payer, an operation on either wallet may have used it already,
+// while a hold is captured once.
+func CaptureKey(holdID int64) string {
+	return uuid.NewSHA1(captureNamespace, []byte(strconv.FormatInt(holdID, 10))).String()
+}
+
+type HoldStatus string
+
+const (
+	HoldAuthorized HoldStatus = "authorized"
+	HoldCaptured   HoldStatus = "captured"
+	HoldVoided     HoldStatus = "voided"
+	HoldExpired    HoldStatus = "expired"
+)
+
+// Authorization reserves Value minor units of the source wallet for a payment to the destination wallet
+// until ExpiresAt.
+type Authorization struct {
+	FromWalletID   int64
+	ToWalletID     int64
+	Value          int64
+	Currency       string
+	ExpiresAt      time.Time
+	Initiator      string
+	IdempotencyKey string
+}
+
+type Hold struct {
+	ID             int64      `db:"id"`
+	TransactionID  int64      `db:"transaction_id"`
+	FromWalletID   int64      `db:"from_wallet_id"`
+	ToWalletID     int64      `db:"to_wallet_id"`
+	Value          int64      `db:"value"`
+	CapturedValue  int64      `db:"captured_value"`
+	Currency       string     `db:"currency"`
+	Status         HoldStatus `db:"status"`
+	IdempotencyKey string     `db:"idempotency_key"`
+	ExpiresAt      time.Time  `db:"expires_at"`
+}
+
+// Capture pays Value minor units of the hold to the destination wallet, zero Value captures the whole hold.
+// The rest of the hold is released.
+type Capture struct {
+	HoldID int64
+	Value  int64
+	Now    time.Time
+}
+
+func (s *Storage) GetHold(ctx context.Context, holdID int64) (Hold, error) {
+	var hold Hold
+	err := s.db.GetContext(ctx, &hold, selectHoldQuery, holdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Hold{}, ErrHoldNotFound
+	}
+	if err != nil {
+		return Hold{}, fmt.Errorf("getting hold from storage: %w", err)
+	}
+
+	return hold, nil
+}
+
+// AuthorizeHold reduces the available balance of the source wallet, its value is not changed until capture.
+func (s *Storage) AuthorizeHold(ctx context.Context, info Authorization) (hold Hold, err error) {
+	err = retry(ctx, "authorize hold", func(ctx context.Context) error {
+		hold, err = s.authorizeHold(ctx, info)
+		return err
+	})
+
+	return
+}
+
+func (s *Storage) authorizeHold(ctx context.Context, info Authorization) (hold Hold, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Hold{}, fmt.Errorf("beginning authorize hold tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback authorize hold tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting authorize hold tx: %w", err)
+		}
+	}()
+
+	transactionID, err := insertTransaction(ctx, tx, TransactionAuthorization, TransactionAuthorized, info.Initiator,
+		info.IdempotencyKey)
+	if err != nil {
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, insertHoldQuery, transactionID, info.FromWalletID, info.ToWalletID, info.Value,
+		info.Currency, HoldAuthorized, info.IdempotencyKey, info.ExpiresAt).Scan(holdFields(&hold)...)
+	if err != nil {
+		err = fmt.Errorf("executing inserting hold: %w", translateError(err))
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, updateHeldQuery, info.FromWalletID, info.Value); err != nil {
+		err = fmt.Errorf("executing updating held funds: %w", translateError(err))
+	}
+
+	return
+}
+
+// CaptureHold moves the captured value from the source to the destination wallet
+// and releases the whole hold. Legs of the payment reference the transaction of the hold.
+func (s *Storage) CaptureHold(ctx context.Context, info Capture) (hold Hold, err error) {
+	err = retry(ctx, "capture hold", func(ctx context.Context) error {
+		hold, err = s.captureHold(ctx, info)
+		return err
+	})
+
+	return
+}
+
+func (s *Storage) captureHold(ctx context.Context, info Capture) (hold Hold, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Hold{}, fmt.Errorf("beginning capture hold tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback capture hold tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting capture hold tx: %w", err)
+		}
+	}()
+
+	hold, err = lockAuthorizedHold(ctx, tx, info.HoldID, info.Now)
+	if err != nil {
+		return
+	}
+
+	value := info.Value
+	if value == 0 {
+		value = hold.Value
+	}
+	if value > hold.Value {
+		err = fmt.Errorf("%w: %d of %d", ErrCaptureExceedsHold, value, hold.Value)
+		return
+	}
+
+	if _, err = lockWallets(ctx, tx, hold.FromWalletID, hold.ToWalletID); err != nil {
+		return
+	}
+
+	key := CaptureKey(hold.ID)
+	_, err = tx.ExecContext(ctx, insertTransferLegQuery, hold.TransactionID, hold.FromWalletID, hold.ToWalletID, value,
+		withdrawal, key, nil, nil, nil, nil, nil)
+	if err != nil {
+		err = fmt.Errorf("executing inserting withdrawal money operation: %w", translateError(err))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, insertTransferLegQuery, hold.TransactionID, hold.ToWalletID, hold.FromWalletID, value,
+		deposit, key, nil, nil, nil, nil, nil)
+	if err != nil {
+		err = fmt.Errorf("executing inserting deposit money operation: %w", translateError(err))
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, captureHeldQuery, hold.FromWalletID, value, hold.Value); err != nil {
+		err = fmt.Errorf("executing updating source wallet: %w", translateError(err))
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, updateWalletQuery, hold.ToWalletID, value); err != nil {
+		err = fmt.Errorf("executing updating destination wallet: %w", translateError(err))
+		return
+	}
+
+	err = postJournal(ctx, tx, hold.TransactionID, "capture",
+		posting{walletID: hold.FromWalletID, currency: hold.Currency, amount: -value},
+		posting{walletID: hold.ToWalletID, currency: hold.Currency, amount: value},
+	)
+	if err != nil {
+		return
+	}
+
+	hold.Status, hold.CapturedValue = HoldCaptured, value
+	err = closeHold(ctx, tx, hold, TransactionCaptured)
+
+	return
+}
+
+// VoidHold releases the hold without moving money.
+func (s *Storage) VoidHold(ctx context.Context, holdID int64, now time.Time) (hold Hold, err error) {
+	err = retry(ctx, "void hold", func(ctx context.Context) error {
+		hold, err = s.voidHold(ctx, holdID, now)
+		return err
+	})
+
+	return
+}
+
+func (s *Storage) voidHold(ctx context.Context, holdID int64, now time.Time) (hold Hold, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Hold{}, fmt.Errorf("beginning void hold tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback void hold tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting void hold tx: %w", err)
+		}
+	}()
+
+	hold, err = lockAuthorizedHold(ctx, tx, holdID, now)
+	if err != nil {
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, updateHeldQuery, hold.FromWalletID, -hold.Value); err != nil {
+		err = fmt.Errorf("executing updating held funds: %w", translateError(err))
+		return
+	}
+
+	hold.Status = HoldVoided
+	err = closeHold(ctx, tx, hold, TransactionVoided)
+
+	return
+}
+
+// ExpireHolds releases authorized holds which expired by now and returns how many of them there were.
+func (s *Storage) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	var expired int64
+	err := s.db.QueryRowContext(ctx, expireHoldsQuery, now, HoldExpired, HoldAuthorized).Scan(&expired)
+	if err != nil {
+		return 0, fmt.Errorf("expiring holds: %w", translateError(err))
+	}
+
+	return expired, nil
+}
+
+// lockAuthorizedHold fails when the hold is not authorized anymore, including the expired one
+// which the sweeper has not released yet.
+func lockAuthorizedHold(ctx context.Context, tx *sql.Tx, holdID int64, now time.Time) (Hold, error) {
+	var hold Hold
+	err := tx.QueryRowContext(ctx, selectHoldForUpdateQuery, holdID).Scan(holdFields(&hold)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Hold{}, ErrHoldNotFound
+	}
+	if err != nil {
+		return Hold{}, fmt.Errorf("executing selecting hold: %w", err)
+	}
+
+	if hold.Status != HoldAuthorized {
+		return Hold{}, fmt.Errorf("%w: hold %d is %s", ErrHoldNotAuthorized, hold.ID, hold.Status)
+	}
+
+	if !now.Before(hold.ExpiresAt) {
+		return Hold{}, fmt.Errorf("%w: hold %d is %s", ErrHoldNotAuthorized, hold.ID, HoldExpired)
+	}
+
+	return hold, nil
+}
+
+func closeHold(ctx context.Context, tx *sql.Tx, hold Hold, status TransactionStatus) error {
+	if _, err := tx.ExecContext(ctx, updateHoldQuery, hold.ID, hold.Status, hold.CapturedValue); err != nil {
+		return fmt.Errorf("executing updating hold: %w", translateError(err))
+	}
+
+	if _, err := tx.ExecContext(ctx, updateTransactionQuery, hold.TransactionID, status); err != nil {
+		return fmt.Errorf("executing updating transaction: %w", translateError(err))
+	}
+
+	return nil
+}
+
+func holdFields(hold *Hold) []interface{} {
+	return []interface{}{&hold.ID, &hold.TransactionID, &hold.FromWalletID, &hold.ToWalletID, &hold.Value,
+		&hold.CapturedValue, &hold.Currency, &hold.Status, &hold.IdempotencyKey, &hold.ExpiresAt}
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	deleteExpiredIdempotencyKeyQuery = "DELETE FROM idempotency_key " +
+		"WHERE endpoint = $1 AND scope = $2 AND key = $3 " +
+		"AND (expires_at <= $4 OR (status_code IS NULL AND reserved_until <= $4))"
+	deleteExpiredIdempotencyKeysQuery = "DELETE FROM idempotency_key WHERE expires_at <= $1"
+	insertIdempotencyKeyQuery         = "INSERT INTO idempotency_key(endpoint, scope, key, fingerprint, reserved_until, expires_at) " +
+		"VALUES (:endpoint, :scope, :key, :fingerprint, :reserved_until, :expires_at) " +
+		"ON CONFLICT (endpoint, scope, key) DO NOTHING"
+	selectIdempotencyKeyQuery = "SELECT endpoint, scope, key, fingerprint, status_code, content_type, response_body, " +
+		"reserved_until, expires_at FROM idempotency_key WHERE endpoint = $1 AND scope = $2 AND key = $3"
+	updateIdempotencyKeyQuery = "UPDATE idempotency_key " +
+		"SET status_code = :status_code, content_type = :content_type, response_body = :response_body " +
+		"WHERE endpoint = :endpoint AND scope = :scope AND key = :key"
+	deletePendingIdempotencyKeyQuery = "DELETE FROM idempotency_key " +
+		"WHERE endpoint = $1 AND scope = $2 AND key = $3 AND status_code IS NULL"
+)
+
+// IdempotencyKey is a request seen by the endpoint. The key is unique within the scope, e.g. the wallet
+// the request acts on. StatusCode is not valid while the request is in progress, a request which is
+// still in progress at ReservedUntil is considered lost.
+type IdempotencyKey struct {
+	Endpoint      string         `db:"endpoint"`
+	Scope         string         `db:"scope"`
+	Key           string         `db:"key"`
+	Fingerprint   string         `db:"fingerprint"`
+	StatusCode    sql.NullInt32  `db:"status_code"`
+	ContentType   sql.NullString `db:"content_type"`
+	ResponseBody  []byte         `db:"response_body"`
+	ReservedUntil time.Time      `db:"reserved_until"`
+	ExpiresAt     time.Time      `db:"expires_at"`
+}
+
+// ReserveIdempotencyKey saves the key unless a not expired one exists already. A pending key is expired
+// after its ReservedUntil.
+// It returns the saved key and whether it was reserved by this call.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key IdempotencyKey, now time.Time) (IdempotencyKey, bool, error) {
+	if _, err := s.db.ExecContext(ctx, deleteExpiredIdempotencyKeyQuery, key.Endpoint, key.Scope, key.Key, now); err != nil {
+		return IdempotencyKey{}, false, fmt.Errorf("deleting expired idempotency key: %w", err)
+	}
+
+	result, err := s.db.NamedExecContext(ctx, insertIdempotencyKeyQuery, key)
+	if err != nil {
+		return IdempotencyKey{}, false, fmt.Errorf("inserting idempotency key: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return IdempotencyKey{}, false, fmt.Errorf("getting inserted idempotency keys: %w", err)
+	}
+	if inserted == 1 {
+		return key, true, nil
+	}
+
+	var saved IdempotencyKey
+	if err := s.db.GetContext(ctx, &saved, selectIdempotencyKeyQuery, key.Endpoint, key.Scope, key.Key); err != nil {
+		return IdempotencyKey{}, false, fmt.Errorf("getting idempotency key from storage: %w", err)
+	}
+
+	return saved, false, nil
+}
+
+// CompleteIdempotencyKey saves the response of the reserved key to replay it on retries.
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key IdempotencyKey) error {
+	if _, err := s.db.NamedExecContext(ctx, updateIdempotencyKeyQuery, key); err != nil {
+		return fmt.Errorf("updating idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the keys which expired by now and returns how many of them there were.
+func (s *Storage) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	result, err := s.db.ExecContext(ctx, deleteExpiredIdempotencyKeysQuery, now)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired idempotency keys: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("getting deleted idempotency keys: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// ReleaseIdempotencyKey deletes the reserved key, so the request can be retried.
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key IdempotencyKey) error {
+	if _, err := s.db.ExecContext(ctx, deletePendingIdempotencyKeyQuery, key.Endpoint, key.Scope, key.Key); err != nil {
+		return fmt.Errorf("deleting idempotency key: %w", err)
+	}
+
+	return nil
+}
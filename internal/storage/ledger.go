@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const (
+	insertWalletAccountQuery = "INSERT INTO account(wallet_id, currency) VALUES ($1, $2)"
+	selectWalletAccountQuery = "SELECT id, currency FROM account WHERE wallet_id = $1"
+	// the no-op update makes RETURNING work for an already existing account
+	upsertSystemAccountQuery = "INSERT INTO account(code, currency) VALUES ($1, $2) " +
+		"ON CONFLICT (code, currency) WHERE code IS NOT NULL DO UPDATE SET code = EXCLUDED.code RETURNING id"
+	insertJournalEntryQuery = "INSERT INTO journal_entry(transaction_id, description) VALUES ($1, $2) RETURNING id"
+	insertPostingQuery      = "INSERT INTO posting(journal_entry_id, account_id, amount, currency) VALUES ($1, $2, $3, $4)"
+)
+
+// SystemAccount is the counterparty of wallets for money entering and leaving the system.
+type SystemAccount string
+
+const (
+	// SystemDeposits is debited by deposits.
+	SystemDeposits SystemAccount = "deposits"
+	// SystemWithdrawals is credited by withdrawals.
+	SystemWithdrawals SystemAccount = "withdrawals"
+	// SystemFees is credited by fees charged from wallets.
+	SystemFees SystemAccount = "fees"
+	// SystemFX balances both currencies of a cross-currency transfer.
+	SystemFX SystemAccount = "fx"
+)
+
+// posting changes the balance of an account by a signed amount of minor units.
+// Either walletID or system names the account.
+type posting struct {
+	walletID int64
+	system   SystemAccount
+	currency string
+	amount   int64
+}
+
+// postJournal records a journal entry of the transaction. Postings must sum to zero in every currency,
+// otherwise the storage transaction fails on commit.
+func postJournal(ctx context.Context, tx *sql.Tx, transactionID int64, description string, postings ...posting) error {
+	var journalEntryID int64
+	err := tx.QueryRowContext(ctx, insertJournalEntryQuery, transactionID, description).Scan(&journalEntryID)
+	if err != nil {
+		return fmt.Errorf("executing inserting journal entry: %w", translateError(err))
+	}
+
+	for _, p := range postings {
+		accountID, err := accountID(ctx, tx, p)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, insertPostingQuery, journalEntryID, accountID, p.amount, p.currency)
+		if err != nil {
+			return fmt.Errorf("executing inserting posting: %w", translateError(err))
+		}
+	}
+
+	return nil
+}
+
+func accountID(ctx context.Context, tx *sql.Tx, p posting) (int64, error) {
+	var id int64
+	if p.system != "" {
+		err := tx.QueryRowContext(ctx, upsertSystemAccountQuery, p.system, p.currency).Scan(&id)
+		if err != nil {
+			return 0, fmt.Errorf("executing upserting %s account: %w", p.system, err)
+		}
+
+		return id, nil
+	}
+
+	var currency string
+	err := tx.QueryRowContext(ctx, selectWalletAccountQuery, p.walletID).Scan(&id, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: no account of wallet %d", ErrWalletNotFound, p.walletID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("executing selecting wallet account: %w", err)
+	}
+
+	if currency != p.currency {
+		return 0, fmt.Errorf("posting %s to account of %s wallet %d", p.currency, currency, p.walletID)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_transferPostings(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer Transfer
+	}{
+		{
+			name:     "same currency",
+			transfer: Transfer{FromWalletID: 1, ToWalletID: 2, Value: 150, Currency: "USD", DestinationValue: 150},
+		},
+		{
+			name: "cross currency",
+			transfer: Transfer{
+				FromWalletID:     1,
+				ToWalletID:       2,
+				Value:            150,
+				Currency:         "USD",
+				DestinationValue: 217,
+				Exchange: &Exchange{
+					Rate:                "145.12",
+					SourceCurrency:      "USD",
+					DestinationCurrency: "JPY",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sums := make(map[string]int64)
+			for _, p := range transferPostings(tt.transfer) {
+				sums[p.currency] += p.amount
+			}
+			for currency, sum := range sums {
+				require.Zero(t, sum, "postings in %s are not balanced", currency)
+			}
+		})
+	}
+}
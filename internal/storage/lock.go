@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const selectWalletForUpdateQuery = "SELECT id, currency, value, held FROM wallet WHERE id = $1 FOR UPDATE"
+
+// lockWallets locks rows of the wallets in ascending id order, whatever order they are passed in,
+// so transactions touching the same wallets wait for each other instead of deadlocking.
+// The locked wallets are returned by id with their current balances.
+func lockWallets(ctx context.Context, tx *sql.Tx, walletIDs ...int64) (map[int64]Wallet, error) {
+	ids := make([]int64, len(walletIDs))
+	copy(ids, walletIDs)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	wallets := make(map[int64]Wallet, len(ids))
+	for _, id := range ids {
+		if _, ok := wallets[id]; ok {
+			continue
+		}
+
+		var wallet Wallet
+		err := tx.QueryRowContext(ctx, selectWalletForUpdateQuery, id).
+			Scan(&wallet.ID, &wallet.Currency, &wallet.Value, &wallet.Held)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %d", ErrWalletNotFound, id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("executing locking wallet: %w", err)
+		}
+		wallets[id] = wallet
+	}
+
+	return wallets, nil
+}
+
+// requireAvailable fails with ErrInsufficientFunds when the locked wallet can not spend value.
+func requireAvailable(wallet Wallet, value int64) error {
+	if available := wallet.Value - wallet.Held; available < value {
+		return fmt.Errorf("%w: wallet %d has %d available of %d", ErrInsufficientFunds, wallet.ID, available, value)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"payment-system/internal/storage"
+)
+
+func (s *Storage) GetHold(_ context.Context, holdID int64) (storage.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, ok := s.holds[holdID]
+	if !ok {
+		return storage.Hold{}, storage.ErrHoldNotFound
+	}
+
+	return hold, nil
+}
+
+func (s *Storage) AuthorizeHold(_ context.Context, info storage.Authorization) (storage.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireWallets(info.FromWalletID, info.ToWalletID); err != nil {
+		return storage.Hold{}, err
+	}
+
+	key := operationKey{idempotencyKey: info.IdempotencyKey, walletID: info.FromWalletID}
+	if s.holdKeys[key] {
+		return storage.Hold{}, fmt.Errorf("%w: hold %s on wallet %d", storage.ErrDuplicate, info.IdempotencyKey, info.FromWalletID)
+	}
+
+	if err := s.requireAvailable(info.FromWalletID, info.Value); err != nil {
+		return storage.Hold{}, err
+	}
+
+	transactionID := s.addTransaction(storage.TransactionAuthorization, storage.TransactionAuthorized, info.Initiator, 0)
+	s.lastHoldID++
+	hold := storage.Hold{
+		ID:             s.lastHoldID,
+		TransactionID:  transactionID,
+		FromWalletID:   info.FromWalletID,
+		ToWalletID:     info.ToWalletID,
+		Value:          info.Value,
+		Currency:       info.Currency,
+		Status:         storage.HoldAuthorized,
+		IdempotencyKey: info.IdempotencyKey,
+		ExpiresAt:      info.ExpiresAt,
+	}
+	s.holds[hold.ID] = hold
+	s.holdKeys[key] = true
+	s.updateWallet(info.FromWalletID, 0, info.Value)
+
+	return hold, nil
+}
+
+func (s *Storage) CaptureHold(_ context.Context, info storage.Capture) (storage.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.authorizedHold(info.HoldID, info.Now)
+	if err != nil {
+		return storage.Hold{}, err
+	}
+
+	value := info.Value
+	if value == 0 {
+		value = hold.Value
+	}
+	if value > hold.Value {
+		return storage.Hold{}, fmt.Errorf("%w: %d of %d", storage.ErrCaptureExceedsHold, value, hold.Value)
+	}
+
+	key := storage.CaptureKey(hold.ID)
+	if err := s.requireNewOperations(key, hold.FromWalletID, hold.ToWalletID); err != nil {
+		return storage.Hold{}, err
+	}
+
+	s.addOperation(hold.TransactionID, hold.FromWalletID, hold.ToWalletID, value, withdrawal, key)
+	s.addOperation(hold.TransactionID, hold.ToWalletID, hold.FromWalletID, value, deposit, key)
+	s.updateWallet(hold.FromWalletID, -value, -hold.Value)
+	s.updateWallet(hold.ToWalletID, value, 0)
+
+	hold.Status, hold.CapturedValue = storage.HoldCaptured, value
+	s.closeHold(hold, storage.TransactionCaptured)
+
+	return hold, nil
+}
+
+func (s *Storage) VoidHold(_ context.Context, holdID int64, now time.Time) (storage.Hold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.authorizedHold(holdID, now)
+	if err != nil {
+		return storage.Hold{}, err
+	}
+
+	s.updateWallet(hold.FromWalletID, 0, -hold.Value)
+	hold.Status = storage.HoldVoided
+	s.closeHold(hold, storage.TransactionVoided)
+
+	return hold, nil
+}
+
+func (s *Storage) ExpireHolds(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired int64
+	for _, hold := range s.holds {
+		if hold.Status != storage.HoldAuthorized || hold.ExpiresAt.After(now) {
+			continue
+		}
+
+		s.updateWallet(hold.FromWalletID, 0, -hold.Value)
+		hold.Status = storage.HoldExpired
+		s.closeHold(hold, storage.TransactionExpired)
+		expired++
+	}
+
+	return expired, nil
+}
+
+// authorizedHold fails when the hold is not authorized anymore, including the expired one
+// which was not swept yet.
+func (s *Storage) authorizedHold(holdID int64, now time.Time) (storage.Hold, error) {
+	hold, ok := s.holds[holdID]
+	if !ok {
+		return storage.Hold{}, storage.ErrHoldNotFound
+	}
+
+	if hold.Status != storage.HoldAuthorized {
+		return storage.Hold{}, fmt.Errorf("%w: hold %d is %s", storage.ErrHoldNotAuthorized, hold.ID, hold.Status)
+	}
+
+	if !now.Before(hold.ExpiresAt) {
+		return storage.Hold{}, fmt.Errorf("%w: hold %d is %s", storage.ErrHoldNotAuthorized, hold.ID, storage.HoldExpired)
+	}
+
+	return hold, nil
+}
+
+func (s *Storage) closeHold(hold storage.Hold, status storage.TransactionStatus) {
+	s.holds[hold.ID] = hold
+	s.transactions[hold.TransactionID].Status = status
+}
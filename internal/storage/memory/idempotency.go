@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"payment-system/internal/storage"
+)
+
+type idempotencyKeyID struct {
+	endpoint string
+	scope    string
+	key      string
+}
+
+// ReserveIdempotencyKey saves the key unless a not expired one exists already. A pending key is expired
+// after its ReservedUntil.
+// It returns the saved key and whether it was reserved by this call.
+func (s *Storage) ReserveIdempotencyKey(_ context.Context, key storage.IdempotencyKey, now time.Time) (storage.IdempotencyKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyKeyID{endpoint: key.Endpoint, scope: key.Scope, key: key.Key}
+	if saved, ok := s.idempotencyKeys[id]; ok && saved.ExpiresAt.After(now) &&
+		(saved.StatusCode.Valid || saved.ReservedUntil.After(now)) {
+		return saved, false, nil
+	}
+
+	key.StatusCode, key.ContentType, key.ResponseBody = sql.NullInt32{}, sql.NullString{}, nil
+	s.idempotencyKeys[id] = key
+	return key, true, nil
+}
+
+// CompleteIdempotencyKey saves the response of the reserved key to replay it on retries.
+func (s *Storage) CompleteIdempotencyKey(_ context.Context, key storage.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyKeyID{endpoint: key.Endpoint, scope: key.Scope, key: key.Key}
+	saved, ok := s.idempotencyKeys[id]
+	if !ok {
+		return nil
+	}
+
+	saved.StatusCode, saved.ContentType = key.StatusCode, key.ContentType
+	saved.ResponseBody = append([]byte(nil), key.ResponseBody...)
+	s.idempotencyKeys[id] = saved
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the keys which expired by now and returns how many of them there were.
+func (s *Storage) DeleteExpiredIdempotencyKeys(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, key := range s.idempotencyKeys {
+		if !key.ExpiresAt.After(now) {
+			delete(s.idempotencyKeys, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// ReleaseIdempotencyKey deletes the reserved key, so the request can be retried.
+func (s *Storage) ReleaseIdempotencyKey(_ context.Context, key storage.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyKeyID{endpoint: key.Endpoint, scope: key.Scope, key: key.Key}
+	if saved, ok := s.idempotencyKeys[id]; ok && !saved.StatusCode.Valid {
+		delete(s.idempotencyKeys, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,381 @@
+// Package memory keeps wallets in process memory. It follows the contract of the postgres storage,
+// so it serves unit tests and local development without a database. Data is lost on restart.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"payment-system/internal/storage"
+)
+
+const (
+	deposit    storage.Direction = 0
+	withdrawal storage.Direction = 1
+)
+
+// operationKey is unique like the idempotency key of an operation on a wallet in postgres.
+type operationKey struct {
+	idempotencyKey string
+	walletID       int64
+}
+
+// Storage is safe for concurrent use, every call holds a single lock, so money moves are atomic.
+type Storage struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	wallets         map[int64]storage.Wallet
+	walletKeys      map[string]bool
+	transactions    map[int64]*storage.Transaction
+	operations      []storage.Operation
+	operationKeys   map[operationKey]bool
+	holds           map[int64]storage.Hold
+	holdKeys        map[operationKey]bool
+	quotes          map[string]storage.Quote
+	idempotencyKeys map[idempotencyKeyID]storage.IdempotencyKey
+
+	lastWalletID      int64
+	lastTransactionID int64
+	lastOperationID   int64
+	lastHoldID        int64
+}
+
+func New() *Storage {
+	return &Storage{
+		now:             time.Now,
+		wallets:         make(map[int64]storage.Wallet),
+		walletKeys:      make(map[string]bool),
+		transactions:    make(map[int64]*storage.Transaction),
+		operationKeys:   make(map[operationKey]bool),
+		holds:           make(map[int64]storage.Hold),
+		holdKeys:        make(map[operationKey]bool),
+		quotes:          make(map[string]storage.Quote),
+		idempotencyKeys: make(map[idempotencyKeyID]storage.IdempotencyKey),
+	}
+}
+
+func (s *Storage) AddWallet(_ context.Context, wallet storage.Wallet) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.walletKeys[wallet.IdempotencyKey] {
+		return 0, fmt.Errorf("%w: wallet %s", storage.ErrDuplicate, wallet.IdempotencyKey)
+	}
+
+	s.lastWalletID++
+	s.walletKeys[wallet.IdempotencyKey] = true
+	s.wallets[s.lastWalletID] = storage.Wallet{
+		ID:        s.lastWalletID,
+		Currency:  wallet.Currency,
+		UpdatedAt: s.now(),
+	}
+
+	return s.lastWalletID, nil
+}
+
+func (s *Storage) GetWallet(_ context.Context, walletID int64) (storage.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wallet, ok := s.wallets[walletID]
+	if !ok {
+		return storage.Wallet{}, storage.ErrWalletNotFound
+	}
+
+	return wallet, nil
+}
+
+func (s *Storage) DepositMoney(_ context.Context, info storage.Deposit) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireWallets(info.WalletID); err != nil {
+		return 0, err
+	}
+
+	if err := s.requireNewOperations(info.IdempotencyKey, info.WalletID); err != nil {
+		return 0, err
+	}
+
+	transactionID := s.addTransaction(storage.TransactionDeposit, storage.TransactionCompleted, info.Initiator, 0)
+	s.addOperation(transactionID, info.WalletID, 0, info.Value, deposit, info.IdempotencyKey)
+	s.updateWallet(info.WalletID, info.Value, 0)
+
+	return transactionID, nil
+}
+
+func (s *Storage) WithdrawMoney(_ context.Context, info storage.Withdrawal) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireWallets(info.WalletID); err != nil {
+		return 0, err
+	}
+
+	if err := s.requireAvailable(info.WalletID, info.Value); err != nil {
+		return 0, err
+	}
+
+	if err := s.requireNewOperations(info.IdempotencyKey, info.WalletID); err != nil {
+		return 0, err
+	}
+
+	transactionID := s.addTransaction(storage.TransactionWithdrawal, storage.TransactionCompleted, info.Initiator, 0)
+	s.addOperation(transactionID, info.WalletID, 0, info.Value, withdrawal, info.IdempotencyKey)
+	s.updateWallet(info.WalletID, -info.Value, 0)
+
+	return transactionID, nil
+}
+
+func (s *Storage) TransferMoney(_ context.Context, info storage.Transfer) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireWallets(info.FromWalletID, info.ToWalletID); err != nil {
+		return 0, err
+	}
+
+	if err := s.requireAvailable(info.FromWalletID, info.Value); err != nil {
+		return 0, err
+	}
+
+	if err := s.requireNewOperations(info.IdempotencyKey, info.FromWalletID, info.ToWalletID); err != nil {
+		return 0, err
+	}
+
+	var quoteID string
+	if info.Exchange != nil {
+		quoteID = info.Exchange.QuoteID
+	}
+	if err := s.requireUnusedQuote(quoteID); err != nil {
+		return 0, err
+	}
+
+	transactionID := s.addTransaction(storage.TransactionTransfer, storage.TransactionCompleted, info.Initiator, 0)
+	if quoteID != "" {
+		quote := s.quotes[quoteID]
+		quote.TransactionID = sql.NullInt64{Int64: transactionID, Valid: true}
+		s.quotes[quoteID] = quote
+	}
+	s.addOperation(transactionID, info.FromWalletID, info.ToWalletID, info.Value, withdrawal, info.IdempotencyKey)
+	s.addOperation(transactionID, info.ToWalletID, info.FromWalletID, info.DestinationValue, deposit, info.IdempotencyKey)
+	s.updateWallet(info.FromWalletID, -info.Value, 0)
+	s.updateWallet(info.ToWalletID, info.DestinationValue, 0)
+
+	return transactionID, nil
+}
+
+func (s *Storage) GetOperations(_ context.Context, filter storage.Filter) ([]storage.Operation, error) {
+	from, err := parseDate(filter.From)
+	if err != nil {
+		return nil, fmt.Errorf("getting operations from storage: %w", err)
+	}
+
+	to, err := parseDate(filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("getting operations from storage: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operations := make([]storage.Operation, 0, filter.Limit)
+	for _, operation := range s.operations {
+		switch {
+		case operation.WalletID != filter.WalletID:
+			continue
+		case !from.IsZero() && operation.CreatedAt.Before(from):
+			continue
+		case !to.IsZero() && !operation.CreatedAt.Before(to.AddDate(0, 0, 1)):
+			continue
+		case filter.Direction != nil && operation.Direction != *filter.Direction:
+			continue
+		case filter.After != nil && !after(operation, *filter.After):
+			continue
+		}
+
+		operation.OriginalTransactionID = s.transactions[operation.TransactionID].OriginalTransactionID
+		operations = append(operations, operation)
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		return after(operations[j], storage.Cursor{CreatedAt: operations[i].CreatedAt, ID: operations[i].ID})
+	})
+	if len(operations) > filter.Limit {
+		operations = operations[:filter.Limit]
+	}
+
+	return operations, nil
+}
+
+func (s *Storage) GetTransaction(_ context.Context, transactionID int64) (storage.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.transactions[transactionID]
+	if !ok {
+		return storage.Transaction{}, storage.ErrTransactionNotFound
+	}
+
+	result := *t
+	result.Legs = make([]storage.Leg, 0, 2)
+	for _, operation := range s.operations {
+		if operation.TransactionID != transactionID {
+			continue
+		}
+
+		result.Legs = append(result.Legs, storage.Leg{
+			OperationID:          operation.ID,
+			WalletID:             operation.WalletID,
+			CounterpartyWalletID: operation.CounterpartyWalletID,
+			Value:                operation.Value,
+			Direction:            operation.Direction,
+			Currency:             s.wallets[operation.WalletID].Currency,
+			CreatedAt:            operation.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *Storage) AddQuote(_ context.Context, quote storage.Quote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.quotes[quote.ID]; ok {
+		return fmt.Errorf("%w: quote %s", storage.ErrDuplicate, quote.ID)
+	}
+
+	s.quotes[quote.ID] = quote
+	return nil
+}
+
+func (s *Storage) GetQuote(_ context.Context, quoteID string) (storage.Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quote, ok := s.quotes[quoteID]
+	if !ok {
+		return storage.Quote{}, storage.ErrQuoteNotFound
+	}
+
+	return quote, nil
+}
+
+// requireUnusedQuote fails when a transfer uses a locked quote which is missing or already used,
+// an empty id stands for the current rate.
+func (s *Storage) requireUnusedQuote(quoteID string) error {
+	if quoteID == "" {
+		return nil
+	}
+
+	quote, ok := s.quotes[quoteID]
+	if !ok {
+		return fmt.Errorf("%w: %s", storage.ErrQuoteNotFound, quoteID)
+	}
+	if quote.TransactionID.Valid {
+		return fmt.Errorf("%w: %s", storage.ErrQuoteUsed, quoteID)
+	}
+
+	return nil
+}
+
+// requireWallets fails like the foreign keys of postgres do when a wallet does not exist.
+func (s *Storage) requireWallets(walletIDs ...int64) error {
+	for _, walletID := range walletIDs {
+		if _, ok := s.wallets[walletID]; !ok {
+			return fmt.Errorf("%w: %d", storage.ErrWalletNotFound, walletID)
+		}
+	}
+
+	return nil
+}
+
+// requireAvailable fails like the balance checks of postgres do when value is more than the wallet can spend.
+func (s *Storage) requireAvailable(walletID int64, value int64) error {
+	wallet := s.wallets[walletID]
+	if available := wallet.Value - wallet.Held; available < value {
+		return fmt.Errorf("%w: wallet %d has %d available of %d", storage.ErrInsufficientFunds, walletID, available, value)
+	}
+
+	return nil
+}
+
+// requireNewOperations fails like the unique index of postgres does on an idempotency key reused on a wallet.
+func (s *Storage) requireNewOperations(idempotencyKey string, walletIDs ...int64) error {
+	for _, walletID := range walletIDs {
+		if s.operationKeys[operationKey{idempotencyKey: idempotencyKey, walletID: walletID}] {
+			return fmt.Errorf("%w: operation %s on wallet %d", storage.ErrDuplicate, idempotencyKey, walletID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) addTransaction(transactionType storage.TransactionType, status storage.TransactionStatus,
+	initiator string, originalTransactionID int64) int64 {
+	s.lastTransactionID++
+	s.transactions[s.lastTransactionID] = &storage.Transaction{
+		ID:                    s.lastTransactionID,
+		Type:                  transactionType,
+		Status:                status,
+		Initiator:             initiator,
+		OriginalTransactionID: sql.NullInt64{Int64: originalTransactionID, Valid: originalTransactionID != 0},
+		CreatedAt:             s.now(),
+	}
+
+	return s.lastTransactionID
+}
+
+func (s *Storage) addOperation(transactionID, walletID, counterpartyWalletID, value int64, direction storage.Direction,
+	idempotencyKey string) {
+	s.lastOperationID++
+	createdAt := s.now()
+	s.operations = append(s.operations, storage.Operation{
+		ID:                   s.lastOperationID,
+		TransactionID:        transactionID,
+		WalletID:             walletID,
+		CounterpartyWalletID: sql.NullInt64{Int64: counterpartyWalletID, Valid: counterpartyWalletID != 0},
+		Value:                value,
+		Direction:            direction,
+		Date:                 createdAt.UTC().Format("2006-01-02"),
+		CreatedAt:            createdAt,
+		IdempotencyKey:       idempotencyKey,
+	})
+	s.operationKeys[operationKey{idempotencyKey: idempotencyKey, walletID: walletID}] = true
+}
+
+func (s *Storage) updateWallet(walletID, value, held int64) {
+	wallet := s.wallets[walletID]
+	wallet.Value += value
+	wallet.Held += held
+	wallet.UpdatedAt = s.now()
+	s.wallets[walletID] = wallet
+}
+
+// after tells whether the operation goes after the cursor in the order of creation time and id.
+func after(operation storage.Operation, cursor storage.Cursor) bool {
+	if operation.CreatedAt.Equal(cursor.CreatedAt) {
+		return operation.ID > cursor.ID
+	}
+
+	return operation.CreatedAt.After(cursor.CreatedAt)
+}
+
+func parseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing date: %w", err)
+	}
+
+	return parsed, nil
+}
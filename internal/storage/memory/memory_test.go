@@ -0,0 +1,12 @@
+package memory_test
+
+import (
+	"testing"
+
+	"payment-system/internal/storage/memory"
+	"payment-system/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, memory.New())
+}
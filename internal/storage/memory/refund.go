@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"payment-system/internal/storage"
+)
+
+// RefundTransaction follows the postgres storage: a cross-currency transfer is refunded at its original rate
+// and the shares are rounded down cumulatively, so the last refund returns the rest exactly.
+func (s *Storage) RefundTransaction(_ context.Context, info storage.Refund) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	original, ok := s.transactions[info.TransactionID]
+	if !ok {
+		return 0, storage.ErrTransactionNotFound
+	}
+
+	if !refundable(original.Type, original.Status) {
+		return 0, fmt.Errorf("%w: %s transaction %d is %s", storage.ErrNotRefundable, original.Type, original.ID, original.Status)
+	}
+
+	payer, payee, err := s.paymentLegs(info.TransactionID)
+	if err != nil {
+		return 0, err
+	}
+
+	refunded := original.RefundedValue
+	value := info.Value
+	if value == 0 {
+		value = payee.Value - refunded
+	}
+	if value <= 0 || value > payee.Value-refunded {
+		return 0, fmt.Errorf("%w: %d with %d of %d refunded", storage.ErrRefundExceedsTransaction, value, refunded, payee.Value)
+	}
+
+	if err := s.requireAvailable(payee.WalletID, value); err != nil {
+		return 0, err
+	}
+
+	sourceValue := share(refunded+value, payer.Value, payee.Value) - share(refunded, payer.Value, payee.Value)
+	if sourceValue == 0 {
+		return 0, fmt.Errorf("%w: refund of %d is less than a minor unit of %s", storage.ErrNotRefundable, value,
+			s.wallets[payer.WalletID].Currency)
+	}
+
+	if err := s.requireNewOperations(info.IdempotencyKey, payee.WalletID, payer.WalletID); err != nil {
+		return 0, err
+	}
+
+	transactionID := s.addTransaction(storage.TransactionRefund, storage.TransactionCompleted, info.Initiator, original.ID)
+	s.addOperation(transactionID, payee.WalletID, payer.WalletID, value, withdrawal, info.IdempotencyKey)
+	s.addOperation(transactionID, payer.WalletID, payee.WalletID, sourceValue, deposit, info.IdempotencyKey)
+	s.updateWallet(payee.WalletID, -value, 0)
+	s.updateWallet(payer.WalletID, sourceValue, 0)
+
+	original.RefundedValue += value
+	original.Status = storage.TransactionPartiallyRefunded
+	if original.RefundedValue == payee.Value {
+		original.Status = storage.TransactionRefunded
+	}
+
+	return transactionID, nil
+}
+
+func refundable(transactionType storage.TransactionType, status storage.TransactionStatus) bool {
+	switch status {
+	case storage.TransactionPartiallyRefunded, storage.TransactionRefunded:
+		return true
+	case storage.TransactionCompleted:
+		return transactionType == storage.TransactionTransfer
+	case storage.TransactionCaptured:
+		return transactionType == storage.TransactionAuthorization
+	default:
+		return false
+	}
+}
+
+// paymentLegs returns the withdrawal operation of the payer and the deposit operation of the payee.
+func (s *Storage) paymentLegs(transactionID int64) (payer, payee storage.Operation, err error) {
+	var payers, payees int
+	for _, operation := range s.operations {
+		if operation.TransactionID != transactionID {
+			continue
+		}
+
+		if operation.Direction == withdrawal {
+			payer = operation
+			payers++
+		} else {
+			payee = operation
+			payees++
+		}
+	}
+
+	if payers != 1 || payees != 1 {
+		return storage.Operation{}, storage.Operation{}, fmt.Errorf("%w: transaction %d has %d withdrawal and %d deposit legs",
+			storage.ErrNotRefundable, transactionID, payers, payees)
+	}
+
+	return payer, payee, nil
+}
+
+// share returns value * numerator / denominator rounded down, without overflowing in between.
+func share(value, numerator, denominator int64) int64 {
+	result := new(big.Int).Mul(big.NewInt(value), big.NewInt(numerator))
+	return result.Quo(result, big.NewInt(denominator)).Int64()
+}
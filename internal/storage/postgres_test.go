@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/config"
+	"payment-system/internal/db"
+	"payment-system/internal/storage"
+)
+
+// newPostgresStorage connects to the migrated database configured by the PG* environment like the service.
+// Local runs without PGHOST skip the test, the integration run sets INTEGRATION and fails instead.
+func newPostgresStorage(t *testing.T) *storage.Storage {
+	t.Helper()
+	_, hasHost := os.LookupEnv("PGHOST")
+	if _, integration := os.LookupEnv("INTEGRATION"); integration {
+		require.True(t, hasHost, "integration run requires postgres, set PGHOST")
+	} else if testing.Short() || !hasHost {
+		t.Skip("requires postgres, set PGHOST to run")
+	}
+
+	database, err := db.New(config.Default().DB)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		database.Close()
+	})
+
+	return storage.New(database)
+}
+
+func TestStorage_AddWallet(t *testing.T) {
+	s := newPostgresStorage(t)
+	ctx := context.Background()
+
+	key := uuid.New().String()
+	walletID, err := s.AddWallet(ctx, storage.Wallet{IdempotencyKey: key, Currency: "EUR"})
+	require.NoError(t, err)
+	require.NotZero(t, walletID)
+
+	wallet, err := s.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	require.Equal(t, "EUR", wallet.Currency)
+
+	_, err = s.AddWallet(ctx, storage.Wallet{IdempotencyKey: key, Currency: "EUR"})
+	require.True(t, errors.Is(err, storage.ErrDuplicate), "got error %v", err)
+}
+
+func TestStorage_GetWallet_ReadsHeldMoney(t *testing.T) {
+	s := newPostgresStorage(t)
+	ctx := context.Background()
+
+	payerID, err := s.AddWallet(ctx, storage.Wallet{IdempotencyKey: uuid.New().String(), Currency: "USD"})
+	require.NoError(t, err)
+	merchantID, err := s.AddWallet(ctx, storage.Wallet{IdempotencyKey: uuid.New().String(), Currency: "USD"})
+	require.NoError(t, err)
+
+	_, err = s.DepositMoney(ctx, storage.Deposit{
+		WalletID: payerID, Value: 1000, Currency: "USD", IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+	_, err = s.AuthorizeHold(ctx, storage.Authorization{
+		FromWalletID: payerID, ToWalletID: merchantID, Value: 600, Currency: "USD",
+		ExpiresAt: time.Now().Add(time.Hour), IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+
+	payer, err := s.GetWallet(ctx, payerID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), payer.Value)
+	require.Equal(t, int64(600), payer.Held)
+}
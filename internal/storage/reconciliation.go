@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"payment-system/internal/logging"
+)
+
+const (
+	// balances of every wallet are computed in a single statement, so they come from one snapshot
+	selectBalanceDriftsQuery = "SELECT w.id AS wallet_id, w.currency, w.value AS wallet_balance, " +
+		"COALESCE(o.balance, 0) AS operations_balance, COALESCE(l.balance, 0) AS ledger_balance " +
+		"FROM wallet w " +
+		"LEFT JOIN (SELECT wallet_id, sum(CASE WHEN direction = 0 THEN value ELSE -value END)::BIGINT AS balance " +
+		"FROM operation GROUP BY wallet_id) o ON o.wallet_id = w.id " +
+		"LEFT JOIN (SELECT a.wallet_id, sum(p.amount)::BIGINT AS balance FROM posting p JOIN account a ON a.id = p.account_id " +
+		"WHERE a.wallet_id IS NOT NULL GROUP BY a.wallet_id) l ON l.wallet_id = w.id " +
+		"WHERE w.value <> COALESCE(o.balance, 0) OR w.value <> COALESCE(l.balance, 0) " +
+		"ORDER BY w.id"
+	lockWalletQuery        = "SELECT id FROM wallet WHERE id = $1 FOR UPDATE"
+	selectWalletDriftQuery = "SELECT w.id AS wallet_id, w.currency, w.value AS wallet_balance, " +
+		"(SELECT COALESCE(sum(CASE WHEN direction = 0 THEN value ELSE -value END), 0)::BIGINT " +
+		"FROM operation WHERE wallet_id = w.id) AS operations_balance, " +
+		"(SELECT COALESCE(sum(p.amount), 0)::BIGINT FROM posting p JOIN account a ON a.id = p.account_id " +
+		"WHERE a.wallet_id = w.id) AS ledger_balance " +
+		"FROM wallet w WHERE w.id = $1"
+	insertAdjustmentQuery = "INSERT INTO operation(transaction_id, wallet_id, value, direction, idempotency_key, reference) " +
+		"VALUES ($1, $2, $3, $4, $5, $6)"
+)
+
+// SystemAdjustments balances corrective entries of the reconciliation.
+const SystemAdjustments SystemAccount = "adjustments"
+
+// BalanceDrift compares the wallet balance with the balances recomputed from operations and ledger postings.
+type BalanceDrift struct {
+	WalletID          int64  `db:"wallet_id"`
+	Currency          string `db:"currency"`
+	WalletBalance     int64  `db:"wallet_balance"`
+	OperationsBalance int64  `db:"operations_balance"`
+	LedgerBalance     int64  `db:"ledger_balance"`
+}
+
+// Adjustment brings operations and ledger of the wallet in line with its balance.
+// Reason is recorded for audit.
+type Adjustment struct {
+	WalletID       int64
+	Reason         string
+	Initiator      string
+	IdempotencyKey string
+}
+
+// GetBalanceDrifts returns wallets whose balance differs from their operations or postings.
+func (s *Storage) GetBalanceDrifts(ctx context.Context) ([]BalanceDrift, error) {
+	var drifts []BalanceDrift
+	if err := s.db.SelectContext(ctx, &drifts, selectBalanceDriftsQuery); err != nil {
+		return nil, fmt.Errorf("getting balance drifts from storage: %w", err)
+	}
+
+	return drifts, nil
+}
+
+// AdjustBalance recomputes the drift of the wallet under a lock and records an adjustment operation
+// and journal entry for the difference. The wallet balance itself is left as is.
+// It returns the drift found before the adjustment, transactionID is zero when there is nothing to adjust.
+func (s *Storage) AdjustBalance(ctx context.Context, info Adjustment) (drift BalanceDrift, transactionID int64, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return BalanceDrift{}, 0, fmt.Errorf("beginning adjust balance tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback adjust balance tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting adjust balance tx: %w", err)
+		}
+	}()
+
+	// money moves update the wallet row too, so they can not change the balances until commit
+	var walletID int64
+	err = tx.QueryRowContext(ctx, lockWalletQuery, info.WalletID).Scan(&walletID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrWalletNotFound
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("executing locking wallet: %w", err)
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, selectWalletDriftQuery, info.WalletID).
+		Scan(&drift.WalletID, &drift.Currency, &drift.WalletBalance, &drift.OperationsBalance, &drift.LedgerBalance)
+	if err != nil {
+		err = fmt.Errorf("executing selecting wallet drift: %w", translateError(err))
+		return
+	}
+
+	operationsDiff := drift.WalletBalance - drift.OperationsBalance
+	ledgerDiff := drift.WalletBalance - drift.LedgerBalance
+	if operationsDiff == 0 && ledgerDiff == 0 {
+		return
+	}
+
+	transactionID, err = insertTransaction(ctx, tx, TransactionAdjustment, TransactionCompleted, info.Initiator, info.IdempotencyKey)
+	if err != nil {
+		return
+	}
+
+	if operationsDiff != 0 {
+		direction, value := deposit, operationsDiff
+		if operationsDiff < 0 {
+			direction, value = withdrawal, -operationsDiff
+		}
+
+		_, err = tx.ExecContext(ctx, insertAdjustmentQuery, transactionID, info.WalletID, value, direction,
+			info.IdempotencyKey, info.Reason)
+		if err != nil {
+			err = fmt.Errorf("executing inserting adjustment operation: %w", translateError(err))
+			return
+		}
+	}
+
+	if ledgerDiff != 0 {
+		err = postJournal(ctx, tx, transactionID, "adjustment: "+info.Reason,
+			posting{walletID: info.WalletID, currency: drift.Currency, amount: ledgerDiff},
+			posting{system: SystemAdjustments, currency: drift.Currency, amount: -ledgerDiff},
+		)
+	}
+
+	return
+}
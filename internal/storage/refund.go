@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"payment-system/internal/logging"
+)
+
+const (
+	selectTransactionForUpdateQuery = "SELECT type, status, refunded_value FROM transaction WHERE id = $1 FOR UPDATE"
+	selectPaymentLegsQuery          = "SELECT o.wallet_id, o.value, o.direction, w.currency " +
+		"FROM operation o JOIN wallet w ON w.id = o.wallet_id WHERE o.transaction_id = $1"
+	insertRefundTransactionQuery = "INSERT INTO transaction(type, status, initiator, idempotency_key, original_transaction_id) " +
+		"VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	updateRefundedQuery = "UPDATE transaction SET status = $2, refunded_value = $3 WHERE id = $1"
+)
+
+// Refund returns Value minor units of the original transaction back from its destination wallet,
+// zero Value refunds everything which was not refunded yet.
+type Refund struct {
+	TransactionID  int64
+	Value          int64
+	Initiator      string
+	IdempotencyKey string
+}
+
+// paymentLeg is a leg of the refunded transfer or captured authorization.
+type paymentLeg struct {
+	walletID int64
+	value    int64
+	currency string
+}
+
+// RefundTransaction moves money back along the legs of a transfer or a captured authorization.
+// The original transaction is locked, so concurrent refunds never exceed its value together.
+// A refund of a cross-currency transfer returns the source wallet its share at the original rate.
+func (s *Storage) RefundTransaction(ctx context.Context, info Refund) (transactionID int64, err error) {
+	err = retry(ctx, "refund transaction", func(ctx context.Context) error {
+		transactionID, err = s.refundTransaction(ctx, info)
+		return err
+	})
+
+	return
+}
+
+func (s *Storage) refundTransaction(ctx context.Context, info Refund) (transactionID int64, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning refund transaction tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback refund transaction tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting refund transaction tx: %w", err)
+		}
+	}()
+
+	var transactionType TransactionType
+	var status TransactionStatus
+	var refunded int64
+	err = tx.QueryRowContext(ctx, selectTransactionForUpdateQuery, info.TransactionID).
+		Scan(&transactionType, &status, &refunded)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrTransactionNotFound
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("executing selecting transaction: %w", err)
+		return
+	}
+
+	if !refundable(transactionType, status) {
+		err = fmt.Errorf("%w: %s transaction %d is %s", ErrNotRefundable, transactionType, info.TransactionID, status)
+		return
+	}
+
+	payer, payee, err := paymentLegs(ctx, tx, info.TransactionID)
+	if err != nil {
+		return
+	}
+
+	value := info.Value
+	if value == 0 {
+		value = payee.value - refunded
+	}
+	if value <= 0 || value > payee.value-refunded {
+		err = fmt.Errorf("%w: %d with %d of %d refunded", ErrRefundExceedsTransaction, value, refunded, payee.value)
+		return
+	}
+
+	wallets, err := lockWallets(ctx, tx, payee.walletID, payer.walletID)
+	if err != nil {
+		return
+	}
+
+	if err = requireAvailable(wallets[payee.walletID], value); err != nil {
+		return
+	}
+
+	// shares are rounded down cumulatively, so the last refund returns the rest exactly
+	sourceValue := share(refunded+value, payer.value, payee.value) - share(refunded, payer.value, payee.value)
+	if sourceValue == 0 {
+		err = fmt.Errorf("%w: refund of %d is less than a minor unit of %s", ErrNotRefundable, value, payer.currency)
+		return
+	}
+
+	transactionID, err = insertRefundTransaction(ctx, tx, info)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, insertTransferLegQuery, transactionID, payee.walletID, payer.walletID, value,
+		withdrawal, info.IdempotencyKey, nil, nil, nil, nil, nil)
+	if err != nil {
+		err = fmt.Errorf("executing inserting withdrawal money operation: %w", translateError(err))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, insertTransferLegQuery, transactionID, payer.walletID, payee.walletID, sourceValue,
+		deposit, info.IdempotencyKey, nil, nil, nil, nil, nil)
+	if err != nil {
+		err = fmt.Errorf("executing inserting deposit money operation: %w", translateError(err))
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, updateWalletQuery, payee.walletID, -value); err != nil {
+		err = fmt.Errorf("executing updating refunding wallet: %w", translateError(err))
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, updateWalletQuery, payer.walletID, sourceValue); err != nil {
+		err = fmt.Errorf("executing updating refunded wallet: %w", translateError(err))
+		return
+	}
+
+	reverse := Transfer{
+		FromWalletID:     payee.walletID,
+		ToWalletID:       payer.walletID,
+		Value:            value,
+		Currency:         payee.currency,
+		DestinationValue: sourceValue,
+	}
+	if payee.currency != payer.currency {
+		reverse.Exchange = &Exchange{SourceCurrency: payee.currency, DestinationCurrency: payer.currency}
+	}
+	if err = postJournal(ctx, tx, transactionID, "refund", transferPostings(reverse)...); err != nil {
+		return
+	}
+
+	refunded += value
+	status = TransactionPartiallyRefunded
+	if refunded == payee.value {
+		status = TransactionRefunded
+	}
+	if _, err = tx.ExecContext(ctx, updateRefundedQuery, info.TransactionID, status, refunded); err != nil {
+		err = fmt.Errorf("executing updating refunded transaction: %w", translateError(err))
+	}
+
+	return
+}
+
+// refundable tells whether the transaction paid from one wallet to another. A fully refunded one stays
+// refundable, so one more refund fails on its value.
+func refundable(transactionType TransactionType, status TransactionStatus) bool {
+	switch status {
+	case TransactionPartiallyRefunded, TransactionRefunded:
+		return true
+	case TransactionCompleted:
+		return transactionType == TransactionTransfer
+	case TransactionCaptured:
+		return transactionType == TransactionAuthorization
+	default:
+		return false
+	}
+}
+
+// paymentLegs returns the withdrawal leg of the payer and the deposit leg of the payee.
+func paymentLegs(ctx context.Context, tx *sql.Tx, transactionID int64) (payer, payee paymentLeg, err error) {
+	rows, err := tx.QueryContext(ctx, selectPaymentLegsQuery, transactionID)
+	if err != nil {
+		return paymentLeg{}, paymentLeg{}, fmt.Errorf("executing selecting transaction legs: %w", err)
+	}
+	defer rows.Close()
+
+	var payers, payees int
+	for rows.Next() {
+		var leg paymentLeg
+		var direction Direction
+		if err = rows.Scan(&leg.walletID, &leg.value, &direction, &leg.currency); err != nil {
+			return paymentLeg{}, paymentLeg{}, fmt.Errorf("scanning transaction leg: %w", err)
+		}
+
+		if direction == withdrawal {
+			payer = leg
+			payers++
+		} else {
+			payee = leg
+			payees++
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return paymentLeg{}, paymentLeg{}, fmt.Errorf("iterating transaction legs: %w", err)
+	}
+
+	if payers != 1 || payees != 1 {
+		return paymentLeg{}, paymentLeg{}, fmt.Errorf("%w: transaction %d has %d withdrawal and %d deposit legs",
+			ErrNotRefundable, transactionID, payers, payees)
+	}
+
+	return payer, payee, nil
+}
+
+// share returns value * numerator / denominator rounded down, without overflowing in between.
+func share(value, numerator, denominator int64) int64 {
+	result := new(big.Int).Mul(big.NewInt(value), big.NewInt(numerator))
+	return result.Quo(result, big.NewInt(denominator)).Int64()
+}
+
+func insertRefundTransaction(ctx context.Context, tx *sql.Tx, info Refund) (int64, error) {
+	var transactionID int64
+	err := tx.QueryRowContext(ctx, insertRefundTransactionQuery, TransactionRefund, TransactionCompleted,
+		info.Initiator, info.IdempotencyKey, info.TransactionID).Scan(&transactionID)
+	if err != nil {
+		return 0, fmt.Errorf("executing inserting %s transaction: %w", TransactionRefund, translateError(err))
+	}
+
+	return transactionID, nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_share(t *testing.T) {
+	tests := []struct {
+		name                          string
+		value, numerator, denominator int64
+		want                          int64
+	}{
+		{name: "same currency", value: 400, numerator: 1000, denominator: 1000, want: 400},
+		{name: "rounds down", value: 100, numerator: 150, denominator: 217, want: 69},
+		{name: "whole transfer", value: 217, numerator: 150, denominator: 217, want: 150},
+		{name: "no overflow in between", value: math.MaxInt64, numerator: 2, denominator: 4, want: math.MaxInt64 / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, share(tt.value, tt.numerator, tt.denominator))
+		})
+	}
+}
+
+func Test_refundable(t *testing.T) {
+	tests := []struct {
+		name            string
+		transactionType TransactionType
+		status          TransactionStatus
+		want            bool
+	}{
+		{name: "completed transfer", transactionType: TransactionTransfer, status: TransactionCompleted, want: true},
+		{name: "partially refunded transfer", transactionType: TransactionTransfer, status: TransactionPartiallyRefunded, want: true},
+		{name: "refunded transfer", transactionType: TransactionTransfer, status: TransactionRefunded, want: true},
+		{name: "captured authorization", transactionType: TransactionAuthorization, status: TransactionCaptured, want: true},
+		{name: "authorized authorization", transactionType: TransactionAuthorization, status: TransactionAuthorized, want: false},
+		{name: "deposit", transactionType: TransactionDeposit, status: TransactionCompleted, want: false},
+		{name: "refund", transactionType: TransactionRefund, status: TransactionCompleted, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, refundable(tt.transactionType, tt.status))
+		})
+	}
+}
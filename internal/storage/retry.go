@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx"
+
+	"payment-system/internal/logging"
+	"payment-system/internal/spanlog"
+)
+
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+
+	// maxTxAttempts bounds how many times a storage transaction is run when it loses a lock conflict.
+	maxTxAttempts = 3
+)
+
+// txRetryBackoff is the delay before the first retry, it doubles with every next one.
+var txRetryBackoff = 20 * time.Millisecond
+
+// retry runs the storage transaction fn again when postgres aborted it because of a serialization failure
+// or a deadlock. Retries are delayed with a jittered exponential backoff, so conflicting transactions
+// do not collide again. ErrConcurrentUpdate is returned when all attempts failed, the error of the context
+// when it is done before the next attempt.
+// The attempts are traced in one span, fn runs with its context.
+func retry(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	ctx, span := spanlog.Start(ctx, "storage.tx", "tx", name)
+	defer span.End(&err)
+
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		span.SetAttributes("attempts", attempt)
+		err := fn(ctx)
+		if err == nil || !retryable(err) {
+			return err
+		}
+
+		if attempt == maxTxAttempts {
+			return fmt.Errorf("%w: %s tx failed %d times: %s", ErrConcurrentUpdate, name, attempt, err)
+		}
+
+		logging.FromContext(ctx).Warn("retrying tx", "tx", name, "attempt", attempt, "error", err)
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s tx not retried after %d attempts: %s", ctx.Err(), name, attempt, err)
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(err error) bool {
+	var pgErr pgx.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode
+}
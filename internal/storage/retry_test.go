@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_retry(t *testing.T) {
+	txRetryBackoff = time.Millisecond
+	deadlock := fmt.Errorf("commiting transfer money tx: %w", pgx.PgError{Code: deadlockDetectedCode})
+	tests := []struct {
+		name         string
+		errs         []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{name: "success", errs: []error{nil}, wantAttempts: 1},
+		{name: "success after deadlock", errs: []error{deadlock, nil}, wantAttempts: 2},
+		{
+			name:         "success after serialization failure",
+			errs:         []error{pgx.PgError{Code: serializationFailureCode}, nil},
+			wantAttempts: 2,
+		},
+		{name: "no retry of other errors", errs: []error{ErrInsufficientFunds}, wantErr: ErrInsufficientFunds, wantAttempts: 1},
+		{
+			name:         "bounded attempts",
+			errs:         []error{deadlock, deadlock, deadlock, nil},
+			wantErr:      ErrConcurrentUpdate,
+			wantAttempts: maxTxAttempts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(context.Background(), "test", func(context.Context) error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+			require.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+			require.Equal(t, tt.wantAttempts, attempts)
+		})
+	}
+}
+
+func Test_retry_StopsWhenContextIsDone(t *testing.T) {
+	defer func(backoff time.Duration) { txRetryBackoff = backoff }(txRetryBackoff)
+	txRetryBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := retry(ctx, "test", func(context.Context) error {
+		attempts++
+		cancel()
+		return pgx.PgError{Code: deadlockDetectedCode}
+	})
+	require.True(t, errors.Is(err, context.Canceled), "got error %v", err)
+	require.Equal(t, 1, attempts)
+}
+
+func Test_requireAvailable(t *testing.T) {
+	wallet := Wallet{ID: 1, Value: 1000, Held: 300}
+	require.NoError(t, requireAvailable(wallet, 700))
+	require.True(t, errors.Is(requireAvailable(wallet, 701), ErrInsufficientFunds))
+}
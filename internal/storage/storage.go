@@ -2,20 +2,48 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-)
 
-const defaultOperationsCapacity = 1000
+	"payment-system/internal/logging"
+)
 
 const (
-	insertWalletQuery     = "INSERT INTO wallet(idempotency_key) VALUES (:idempotency_key) RETURNING id"
-	insertOperationQuery  = "INSERT INTO operation(wallet_id, value, Direction, idempotency_key) VALUES ($1, $2, $3, $4)"
-	updateWalletQuery     = "UPDATE wallet SET value = value + $2 WHERE id = $1"
-	selectOperationsQuery = "SELECT wallet_id, value, direction, to_char(date, 'YYYY-MM-DD') as date FROM operation " +
-		"WHERE wallet_id = $1 AND date = $2 AND direction = $3"
+	insertWalletQuery      = "INSERT INTO wallet(idempotency_key, currency) VALUES ($1, $2) RETURNING id"
+	selectWalletQuery      = "SELECT id, currency, value, held, updated_at FROM wallet WHERE id = $1"
+	insertTransactionQuery = "INSERT INTO transaction(type, status, initiator, idempotency_key) " +
+		"VALUES ($1, $2, $3, $4) RETURNING id"
+	insertOperationQuery = "INSERT INTO operation(transaction_id, wallet_id, value, direction, idempotency_key) " +
+		"VALUES ($1, $2, $3, $4, $5)"
+	insertWithdrawalQuery = "INSERT INTO operation(transaction_id, wallet_id, value, direction, idempotency_key, reference) " +
+		"VALUES ($1, $2, $3, $4, $5, $6)"
+	insertTransferLegQuery = "INSERT INTO operation(transaction_id, wallet_id, counterparty_wallet_id, value, direction, " +
+		"idempotency_key, fx_rate, source_value, source_currency, destination_value, destination_currency) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
+	updateWalletQuery     = "UPDATE wallet SET value = value + $2, updated_at = now() WHERE id = $1"
+	selectOperationsQuery = "SELECT o.id, o.transaction_id, t.original_transaction_id, o.wallet_id, " +
+		"o.counterparty_wallet_id, o.value, o.direction, to_char(o.date, 'YYYY-MM-DD') as date, o.created_at, " +
+		"o.idempotency_key " +
+		"FROM operation o JOIN transaction t ON t.id = o.transaction_id WHERE o.wallet_id = $1 " +
+		"AND ($2::DATE IS NULL OR o.created_at >= $2::DATE::TIMESTAMP AT TIME ZONE 'UTC') " +
+		"AND ($3::DATE IS NULL OR o.created_at < ($3::DATE + 1)::TIMESTAMP AT TIME ZONE 'UTC') " +
+		"AND ($4::SMALLINT IS NULL OR o.direction = $4::SMALLINT) " +
+		"AND ($5::TIMESTAMPTZ IS NULL OR (o.created_at, o.id) > ($5::TIMESTAMPTZ, $6::BIGINT)) " +
+		"ORDER BY o.created_at, o.id LIMIT $7"
+	selectTransactionQuery = "SELECT id, type, status, initiator, original_transaction_id, refunded_value, created_at " +
+		"FROM transaction WHERE id = $1"
+	selectLegsQuery = "SELECT o.id, o.wallet_id, o.counterparty_wallet_id, o.value, o.direction, w.currency, o.created_at " +
+		"FROM operation o JOIN wallet w ON w.id = o.wallet_id WHERE o.transaction_id = $1 ORDER BY o.id"
+	insertQuoteQuery = "INSERT INTO fx_quote(id, from_currency, to_currency, rate, expires_at) " +
+		"VALUES (:id, :from_currency, :to_currency, :rate, :expires_at)"
+	selectQuoteQuery = "SELECT id, from_currency, to_currency, rate::TEXT AS rate, expires_at, transaction_id " +
+		"FROM fx_quote WHERE id = $1"
+	useQuoteQuery    = "UPDATE fx_quote SET transaction_id = $2 WHERE id = $1 AND transaction_id IS NULL"
+	quoteExistsQuery = "SELECT EXISTS(SELECT 1 FROM fx_quote WHERE id = $1)"
 )
 
 type Direction int8
@@ -25,80 +53,229 @@ const (
 	withdrawal Direction = 1
 )
 
+type TransactionType string
+
+const (
+	TransactionDeposit    TransactionType = "deposit"
+	TransactionWithdrawal TransactionType = "withdrawal"
+	TransactionTransfer   TransactionType = "transfer"
+	// TransactionAdjustment corrects drift found by the reconciliation.
+	TransactionAdjustment TransactionType = "adjustment"
+	// TransactionAuthorization reserves funds of a payment which is captured later.
+	TransactionAuthorization TransactionType = "authorization"
+	// TransactionRefund returns money of a transfer or a captured authorization, it references the original one.
+	TransactionRefund TransactionType = "refund"
+)
+
+type TransactionStatus string
+
+const (
+	TransactionCompleted TransactionStatus = "completed"
+	// TransactionAuthorized holds funds until the transaction is captured, voided or expired.
+	TransactionAuthorized TransactionStatus = "authorized"
+	TransactionCaptured   TransactionStatus = "captured"
+	TransactionVoided     TransactionStatus = "voided"
+	TransactionExpired    TransactionStatus = "expired"
+	// TransactionPartiallyRefunded and TransactionRefunded replace the status of the original transaction on refund.
+	TransactionPartiallyRefunded TransactionStatus = "partially_refunded"
+	TransactionRefunded          TransactionStatus = "refunded"
+)
+
 type Wallet struct {
-	IdempotencyKey string `db:"idempotency_key"`
+	ID             int64     `db:"id"`
+	IdempotencyKey string    `db:"idempotency_key"`
+	Currency       string    `db:"currency"`
+	Value          int64     `db:"value"`
+	Held           int64     `db:"held"`
+	UpdatedAt      time.Time `db:"updated_at"`
 }
 
 type Deposit struct {
 	WalletID       int64
 	Value          int64
+	Currency       string
+	Initiator      string
 	IdempotencyKey string
 }
 
-type Transfer struct {
-	FromWalletID   int64
-	ToWalletID     int64
+// Withdrawal pays Value minor units out of the wallet to the external Destination.
+type Withdrawal struct {
+	WalletID       int64
 	Value          int64
+	Currency       string
+	Destination    string
+	Initiator      string
 	IdempotencyKey string
 }
 
+// Transfer moves Value minor units of Currency out of the source wallet and DestinationValue minor units
+// into the destination wallet. They differ only for cross-currency transfers described by Exchange.
+type Transfer struct {
+	FromWalletID     int64
+	ToWalletID       int64
+	Value            int64
+	Currency         string
+	DestinationValue int64
+	Exchange         *Exchange
+	Initiator        string
+	IdempotencyKey   string
+}
+
+// Exchange is recorded on both legs of a cross-currency transfer.
+type Exchange struct {
+	Rate                string
+	SourceCurrency      string
+	DestinationCurrency string
+	// QuoteID is the locked quote the rate comes from, empty for the current rate.
+	// A quote is used by a single transfer.
+	QuoteID string
+}
+
+type Quote struct {
+	ID           string    `db:"id"`
+	FromCurrency string    `db:"from_currency"`
+	ToCurrency   string    `db:"to_currency"`
+	Rate         string    `db:"rate"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	// TransactionID is the transfer which used the quote, null while it is unused.
+	TransactionID sql.NullInt64 `db:"transaction_id"`
+}
+
+// Filter selects operations of the wallet, empty bounds and nil Direction are not applied.
+// From and To are dates in UTC. Operations are ordered by creation time and id, After continues
+// right after the given one.
 type Filter struct {
 	WalletID  int64
-	Date      string
-	Direction Direction
+	From      string
+	To        string
+	Direction *Direction
+	After     *Cursor
+	Limit     int
+}
+
+// Cursor points to an operation of the wallet by its position in the order.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
 }
 
 type Operation struct {
-	WalletID  int64     `db:"wallet_id"`
-	Value     int64     `db:"value"`
-	Direction Direction `db:"direction"`
-	Date      string    `db:"date"`
+	ID                    int64         `db:"id"`
+	TransactionID         int64         `db:"transaction_id"`
+	OriginalTransactionID sql.NullInt64 `db:"original_transaction_id"`
+	WalletID              int64         `db:"wallet_id"`
+	CounterpartyWalletID  sql.NullInt64 `db:"counterparty_wallet_id"`
+	Value                 int64         `db:"value"`
+	Direction             Direction     `db:"direction"`
+	Date                  string        `db:"date"`
+	CreatedAt             time.Time     `db:"created_at"`
+	IdempotencyKey        string        `db:"idempotency_key"`
+}
+
+// Transaction groups the operations (legs) of a single deposit, withdrawal or transfer.
+// RefundedValue is in minor units of the destination wallet.
+type Transaction struct {
+	ID                    int64             `db:"id"`
+	Type                  TransactionType   `db:"type"`
+	Status                TransactionStatus `db:"status"`
+	Initiator             string            `db:"initiator"`
+	OriginalTransactionID sql.NullInt64     `db:"original_transaction_id"`
+	RefundedValue         int64             `db:"refunded_value"`
+	CreatedAt             time.Time         `db:"created_at"`
+	Legs                  []Leg             `db:"-"`
+}
+
+type Leg struct {
+	OperationID          int64         `db:"id"`
+	WalletID             int64         `db:"wallet_id"`
+	CounterpartyWalletID sql.NullInt64 `db:"counterparty_wallet_id"`
+	Value                int64         `db:"value"`
+	Direction            Direction     `db:"direction"`
+	Currency             string        `db:"currency"`
+	CreatedAt            time.Time     `db:"created_at"`
 }
 
 type Storage struct {
-	db *sqlx.DB
+	db  *sqlx.DB
+	txs openTxs
 }
 
 func New(db *sqlx.DB) *Storage {
 	return &Storage{db: db}
 }
 
-func (s *Storage) AddWallet(ctx context.Context, wallet Wallet) (int64, error) {
-	rows, err := s.db.NamedQueryContext(ctx, insertWalletQuery, wallet)
+// AddWallet opens the ledger account of the wallet together with the wallet.
+func (s *Storage) AddWallet(ctx context.Context, wallet Wallet) (walletID int64, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("inserting wallet: %w", err)
+		return 0, fmt.Errorf("beginning add wallet tx: %w", err)
 	}
 
-	if rows != nil {
-		defer func(rows *sqlx.Rows) {
-			if err = rows.Close(); err != nil {
-				log.Printf("failed to close rows: %s\n", err)
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback add wallet tx", "error", err)
 			}
-		}(rows)
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting add wallet tx: %w", err)
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, insertWalletQuery, wallet.IdempotencyKey, wallet.Currency).Scan(&walletID)
+	if err != nil {
+		err = fmt.Errorf("inserting wallet: %w", translateError(err))
+		return
 	}
 
-	if !rows.Next() {
-		return 0, fmt.Errorf("preparing to scan inserted wallet id: %w", rows.Err())
+	if _, err = tx.ExecContext(ctx, insertWalletAccountQuery, walletID, wallet.Currency); err != nil {
+		err = fmt.Errorf("inserting wallet account: %w", translateError(err))
 	}
 
-	var walletID int64
-	if err := rows.Scan(&walletID); err != nil {
-		return 0, fmt.Errorf("scanning inserted wallet id: %w", err)
+	return
+}
+
+func (s *Storage) GetWallet(ctx context.Context, walletID int64) (Wallet, error) {
+	var wallet Wallet
+	err := s.db.GetContext(ctx, &wallet, selectWalletQuery, walletID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wallet{}, ErrWalletNotFound
 	}
+	if err != nil {
+		return Wallet{}, fmt.Errorf("getting wallet from storage: %w", err)
+	}
+
+	return wallet, nil
+}
 
-	return walletID, nil
+// DepositMoney returns the id of the created transaction.
+func (s *Storage) DepositMoney(ctx context.Context, info Deposit) (transactionID int64, err error) {
+	err = retry(ctx, "deposit money", func(ctx context.Context) error {
+		transactionID, err = s.depositMoney(ctx, info)
+		return err
+	})
+
+	return
 }
 
-func (s *Storage) DepositMoney(ctx context.Context, info Deposit) (err error) {
+func (s *Storage) depositMoney(ctx context.Context, info Deposit) (transactionID int64, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		err = fmt.Errorf("beginning deposit money tx: %w", err)
+		return 0, fmt.Errorf("beginning deposit money tx: %w", err)
 	}
 
 	defer func() {
 		if err != nil {
 			if err := tx.Rollback(); err != nil {
-				log.Printf("failed to rollback deposit money tx: %s\n", err)
+				logging.FromContext(ctx).Error("failed to rollback deposit money tx", "error", err)
 			}
 			return
 		}
@@ -108,30 +285,126 @@ func (s *Storage) DepositMoney(ctx context.Context, info Deposit) (err error) {
 		}
 	}()
 
-	_, err = tx.ExecContext(ctx, insertOperationQuery, info.WalletID, info.Value, deposit, info.IdempotencyKey)
+	// the wallet is locked before its operation is inserted, so operations are created in commit order
+	if _, err = lockWallets(ctx, tx, info.WalletID); err != nil {
+		return
+	}
+
+	transactionID, err = insertTransaction(ctx, tx, TransactionDeposit, TransactionCompleted, info.Initiator, info.IdempotencyKey)
 	if err != nil {
-		err = fmt.Errorf("executing inserting deposit money operation: %w", err)
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, insertOperationQuery, transactionID, info.WalletID, info.Value, deposit, info.IdempotencyKey)
+	if err != nil {
+		err = fmt.Errorf("executing inserting deposit money operation: %w", translateError(err))
 		return
 	}
 
 	_, err = tx.ExecContext(ctx, updateWalletQuery, info.WalletID, info.Value)
 	if err != nil {
-		err = fmt.Errorf("executing updating wallet: %w", err)
+		err = fmt.Errorf("executing updating wallet: %w", translateError(err))
+		return
+	}
+
+	err = postJournal(ctx, tx, transactionID, "deposit",
+		posting{walletID: info.WalletID, currency: info.Currency, amount: info.Value},
+		posting{system: SystemDeposits, currency: info.Currency, amount: -info.Value},
+	)
+
+	return
+}
+
+// WithdrawMoney returns the id of the created transaction.
+func (s *Storage) WithdrawMoney(ctx context.Context, info Withdrawal) (transactionID int64, err error) {
+	err = retry(ctx, "withdraw money", func(ctx context.Context) error {
+		transactionID, err = s.withdrawMoney(ctx, info)
+		return err
+	})
+
+	return
+}
+
+func (s *Storage) withdrawMoney(ctx context.Context, info Withdrawal) (transactionID int64, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning withdraw money tx: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if err := tx.Rollback(); err != nil {
+				logging.FromContext(ctx).Error("failed to rollback withdraw money tx", "error", err)
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commiting withdraw money tx: %w", err)
+		}
+	}()
+
+	wallets, err := lockWallets(ctx, tx, info.WalletID)
+	if err != nil {
+		return
+	}
+
+	if err = requireAvailable(wallets[info.WalletID], info.Value); err != nil {
+		return
+	}
+
+	transactionID, err = insertTransaction(ctx, tx, TransactionWithdrawal, TransactionCompleted, info.Initiator, info.IdempotencyKey)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, insertWithdrawalQuery, transactionID, info.WalletID, info.Value, withdrawal,
+		info.IdempotencyKey, info.Destination)
+	if err != nil {
+		err = fmt.Errorf("executing inserting withdrawal money operation: %w", translateError(err))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, updateWalletQuery, info.WalletID, -info.Value)
+	if err != nil {
+		err = fmt.Errorf("executing updating wallet: %w", translateError(err))
+		return
 	}
 
+	err = postJournal(ctx, tx, transactionID, "withdrawal to "+info.Destination,
+		posting{walletID: info.WalletID, currency: info.Currency, amount: -info.Value},
+		posting{system: SystemWithdrawals, currency: info.Currency, amount: info.Value},
+	)
+
 	return
 }
 
-func (s *Storage) TransferMoney(ctx context.Context, info Transfer) (err error) {
+// TransferMoney returns the id of the created transaction, both legs reference it.
+func (s *Storage) TransferMoney(ctx context.Context, info Transfer) (transactionID int64, err error) {
+	err = retry(ctx, "transfer money", func(ctx context.Context) error {
+		transactionID, err = s.transferMoney(ctx, info)
+		return err
+	})
+
+	return
+}
+
+func (s *Storage) transferMoney(ctx context.Context, info Transfer) (transactionID int64, err error) {
+	s.txs.add()
+	defer s.txs.done()
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		err = fmt.Errorf("beginning transfer money tx: %w", err)
+		return 0, fmt.Errorf("beginning transfer money tx: %w", err)
 	}
 
 	defer func() {
 		if err != nil {
 			if err := tx.Rollback(); err != nil {
-				log.Printf("failed to rollback transfer money tx: %s\n", err)
+				logging.FromContext(ctx).Error("failed to rollback transfer money tx", "error", err)
 			}
 			return
 		}
@@ -141,39 +414,189 @@ func (s *Storage) TransferMoney(ctx context.Context, info Transfer) (err error)
 		}
 	}()
 
-	_, err = tx.ExecContext(ctx, insertOperationQuery, info.FromWalletID, info.Value, withdrawal, info.IdempotencyKey)
+	var rate, sourceCurrency, destinationCurrency sql.NullString
+	var sourceValue, destinationValue sql.NullInt64
+	if info.Exchange != nil {
+		rate = sql.NullString{String: info.Exchange.Rate, Valid: true}
+		sourceCurrency = sql.NullString{String: info.Exchange.SourceCurrency, Valid: true}
+		destinationCurrency = sql.NullString{String: info.Exchange.DestinationCurrency, Valid: true}
+		sourceValue = sql.NullInt64{Int64: info.Value, Valid: true}
+		destinationValue = sql.NullInt64{Int64: info.DestinationValue, Valid: true}
+	}
+
+	// both wallets are locked before any write, so transfers between them in opposite directions queue up
+	wallets, err := lockWallets(ctx, tx, info.FromWalletID, info.ToWalletID)
 	if err != nil {
-		err = fmt.Errorf("executing inserting withdrawal money operation: %w", err)
 		return
 	}
 
-	_, err = tx.ExecContext(ctx, insertOperationQuery, info.ToWalletID, info.Value, deposit, info.IdempotencyKey)
+	if err = requireAvailable(wallets[info.FromWalletID], info.Value); err != nil {
+		return
+	}
+
+	transactionID, err = insertTransaction(ctx, tx, TransactionTransfer, TransactionCompleted, info.Initiator, info.IdempotencyKey)
 	if err != nil {
-		err = fmt.Errorf("executing inserting deposit money operation: %w", err)
+		return
+	}
+
+	if info.Exchange != nil && info.Exchange.QuoteID != "" {
+		if err = useQuote(ctx, tx, info.Exchange.QuoteID, transactionID); err != nil {
+			return
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, insertTransferLegQuery, transactionID, info.FromWalletID, info.ToWalletID, info.Value,
+		withdrawal, info.IdempotencyKey, rate, sourceValue, sourceCurrency, destinationValue, destinationCurrency)
+	if err != nil {
+		err = fmt.Errorf("executing inserting withdrawal money operation: %w", translateError(err))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, insertTransferLegQuery, transactionID, info.ToWalletID, info.FromWalletID,
+		info.DestinationValue, deposit, info.IdempotencyKey, rate, sourceValue, sourceCurrency, destinationValue,
+		destinationCurrency)
+	if err != nil {
+		err = fmt.Errorf("executing inserting deposit money operation: %w", translateError(err))
 		return
 	}
 
 	_, err = tx.ExecContext(ctx, updateWalletQuery, info.FromWalletID, -info.Value)
 	if err != nil {
-		err = fmt.Errorf("executing updating deposit wallet: %w", err)
+		err = fmt.Errorf("executing updating deposit wallet: %w", translateError(err))
 		return
 	}
 
-	_, err = tx.ExecContext(ctx, updateWalletQuery, info.ToWalletID, info.Value)
+	_, err = tx.ExecContext(ctx, updateWalletQuery, info.ToWalletID, info.DestinationValue)
 	if err != nil {
-		err = fmt.Errorf("executing updating withdrawal wallet: %w", err)
+		err = fmt.Errorf("executing updating withdrawal wallet: %w", translateError(err))
 		return
 	}
 
+	err = postJournal(ctx, tx, transactionID, "transfer", transferPostings(info)...)
+
 	return
 }
 
+// transferPostings moves money between the wallets directly, or through the fx account in both currencies
+// when they differ.
+func transferPostings(info Transfer) []posting {
+	if info.Exchange == nil {
+		return []posting{
+			{walletID: info.FromWalletID, currency: info.Currency, amount: -info.Value},
+			{walletID: info.ToWalletID, currency: info.Currency, amount: info.DestinationValue},
+		}
+	}
+
+	return []posting{
+		{walletID: info.FromWalletID, currency: info.Exchange.SourceCurrency, amount: -info.Value},
+		{system: SystemFX, currency: info.Exchange.SourceCurrency, amount: info.Value},
+		{system: SystemFX, currency: info.Exchange.DestinationCurrency, amount: -info.DestinationValue},
+		{walletID: info.ToWalletID, currency: info.Exchange.DestinationCurrency, amount: info.DestinationValue},
+	}
+}
+
 func (s *Storage) GetOperations(ctx context.Context, filter Filter) ([]Operation, error) {
-	operations := make([]Operation, 0, defaultOperationsCapacity)
-	err := s.db.SelectContext(ctx, &operations, selectOperationsQuery, filter.WalletID, filter.Date, filter.Direction)
+	var from, to sql.NullString
+	var direction sql.NullInt32
+	var afterCreatedAt sql.NullTime
+	var afterID sql.NullInt64
+	if filter.From != "" {
+		from = sql.NullString{String: filter.From, Valid: true}
+	}
+	if filter.To != "" {
+		to = sql.NullString{String: filter.To, Valid: true}
+	}
+	if filter.Direction != nil {
+		direction = sql.NullInt32{Int32: int32(*filter.Direction), Valid: true}
+	}
+	if filter.After != nil {
+		afterCreatedAt = sql.NullTime{Time: filter.After.CreatedAt, Valid: true}
+		afterID = sql.NullInt64{Int64: filter.After.ID, Valid: true}
+	}
+
+	operations := make([]Operation, 0, filter.Limit)
+	err := s.db.SelectContext(ctx, &operations, selectOperationsQuery,
+		filter.WalletID, from, to, direction, afterCreatedAt, afterID, filter.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("getting operations from storage: %w", err)
 	}
 
 	return operations, nil
 }
+
+func (s *Storage) GetTransaction(ctx context.Context, transactionID int64) (Transaction, error) {
+	var transaction Transaction
+	err := s.db.GetContext(ctx, &transaction, selectTransactionQuery, transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Transaction{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return Transaction{}, fmt.Errorf("getting transaction from storage: %w", err)
+	}
+
+	if err = s.db.SelectContext(ctx, &transaction.Legs, selectLegsQuery, transactionID); err != nil {
+		return Transaction{}, fmt.Errorf("getting transaction legs from storage: %w", err)
+	}
+
+	return transaction, nil
+}
+
+func insertTransaction(ctx context.Context, tx *sql.Tx, transactionType TransactionType, status TransactionStatus,
+	initiator, idempotencyKey string) (int64, error) {
+	var transactionID int64
+	err := tx.QueryRowContext(ctx, insertTransactionQuery, transactionType, status, initiator, idempotencyKey).
+		Scan(&transactionID)
+	if err != nil {
+		return 0, fmt.Errorf("executing inserting %s transaction: %w", transactionType, translateError(err))
+	}
+
+	return transactionID, nil
+}
+
+// useQuote records the transfer on its locked quote. The row lock makes a concurrent transfer with the same quote
+// wait, it finds the quote used once this one commits.
+func useQuote(ctx context.Context, tx *sql.Tx, quoteID string, transactionID int64) error {
+	result, err := tx.ExecContext(ctx, useQuoteQuery, quoteID, transactionID)
+	if err != nil {
+		return fmt.Errorf("executing using quote: %w", translateError(err))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting used quotes: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, quoteExistsQuery, quoteID).Scan(&exists); err != nil {
+		return fmt.Errorf("checking quote: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrQuoteNotFound, quoteID)
+	}
+
+	return fmt.Errorf("%w: %s", ErrQuoteUsed, quoteID)
+}
+
+func (s *Storage) AddQuote(ctx context.Context, quote Quote) error {
+	if _, err := s.db.NamedExecContext(ctx, insertQuoteQuery, quote); err != nil {
+		return fmt.Errorf("inserting quote: %w", translateError(err))
+	}
+
+	return nil
+}
+
+func (s *Storage) GetQuote(ctx context.Context, quoteID string) (Quote, error) {
+	var quote Quote
+	err := s.db.GetContext(ctx, &quote, selectQuoteQuery, quoteID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Quote{}, ErrQuoteNotFound
+	}
+	if err != nil {
+		return Quote{}, fmt.Errorf("getting quote from storage: %w", err)
+	}
+
+	return quote, nil
+}
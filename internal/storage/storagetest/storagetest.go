@@ -0,0 +1,620 @@
+// Package storagetest is the conformance suite of wallet storages. The postgres and the in-memory storage
+// must both pass it, so the service behaves the same on top of either of them.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"payment-system/internal/storage"
+)
+
+// Storage is the contract of a wallet storage as the wallet service and the idempotency middleware use it.
+type Storage interface {
+	AddWallet(ctx context.Context, wallet storage.Wallet) (int64, error)
+	GetWallet(ctx context.Context, walletID int64) (storage.Wallet, error)
+	DepositMoney(ctx context.Context, info storage.Deposit) (int64, error)
+	WithdrawMoney(ctx context.Context, info storage.Withdrawal) (int64, error)
+	TransferMoney(ctx context.Context, info storage.Transfer) (int64, error)
+	GetTransaction(ctx context.Context, transactionID int64) (storage.Transaction, error)
+	GetOperations(ctx context.Context, filter storage.Filter) ([]storage.Operation, error)
+	AddQuote(ctx context.Context, quote storage.Quote) error
+	GetQuote(ctx context.Context, quoteID string) (storage.Quote, error)
+	AuthorizeHold(ctx context.Context, info storage.Authorization) (storage.Hold, error)
+	CaptureHold(ctx context.Context, info storage.Capture) (storage.Hold, error)
+	GetHold(ctx context.Context, holdID int64) (storage.Hold, error)
+	VoidHold(ctx context.Context, holdID int64, now time.Time) (storage.Hold, error)
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
+	RefundTransaction(ctx context.Context, info storage.Refund) (int64, error)
+	ReserveIdempotencyKey(ctx context.Context, key storage.IdempotencyKey, now time.Time) (storage.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key storage.IdempotencyKey) error
+	ReleaseIdempotencyKey(ctx context.Context, key storage.IdempotencyKey) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Run runs the suite against the storage. Tests create their own wallets with random idempotency keys,
+// so the storage may be shared between them and keep data of earlier runs.
+func Run(t *testing.T, s Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Storage)
+	}{
+		{name: "wallets", test: testWallets},
+		{name: "deposit", test: testDeposit},
+		{name: "withdrawal", test: testWithdrawal},
+		{name: "transfer", test: testTransfer},
+		{name: "operations", test: testOperations},
+		{name: "concurrent transfers", test: testConcurrentTransfers},
+		{name: "quotes", test: testQuotes},
+		{name: "quote used by one transfer", test: testQuoteUsedByOneTransfer},
+		{name: "holds", test: testHolds},
+		{name: "hold expiry", test: testHoldExpiry},
+		{name: "hold key used by a transfer", test: testHoldKeyUsedByTransfer},
+		{name: "refunds", test: testRefunds},
+		{name: "idempotency keys", test: testIdempotencyKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, s)
+		})
+	}
+}
+
+func testWallets(t *testing.T, s Storage) {
+	ctx := context.Background()
+	key := uuid.New().String()
+	walletID, err := s.AddWallet(ctx, storage.Wallet{IdempotencyKey: key, Currency: "USD"})
+	require.NoError(t, err)
+
+	wallet, err := s.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	require.Equal(t, walletID, wallet.ID)
+	require.Equal(t, "USD", wallet.Currency)
+	require.Zero(t, wallet.Value)
+	require.Zero(t, wallet.Held)
+
+	_, err = s.AddWallet(ctx, storage.Wallet{IdempotencyKey: key, Currency: "USD"})
+	requireIs(t, err, storage.ErrDuplicate)
+
+	_, err = s.GetWallet(ctx, -1)
+	requireIs(t, err, storage.ErrWalletNotFound)
+}
+
+func testDeposit(t *testing.T, s Storage) {
+	ctx := context.Background()
+	walletID := addWallet(t, s, "USD")
+	key := uuid.New().String()
+	transactionID, err := s.DepositMoney(ctx, storage.Deposit{
+		WalletID: walletID, Value: 1050, Currency: "USD", Initiator: "test", IdempotencyKey: key,
+	})
+	require.NoError(t, err)
+	requireBalance(t, s, walletID, 1050)
+
+	transaction, err := s.GetTransaction(ctx, transactionID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionDeposit, transaction.Type)
+	require.Equal(t, storage.TransactionCompleted, transaction.Status)
+	require.Equal(t, "test", transaction.Initiator)
+	require.Len(t, transaction.Legs, 1)
+	require.Equal(t, walletID, transaction.Legs[0].WalletID)
+	require.Equal(t, int64(1050), transaction.Legs[0].Value)
+	require.Equal(t, "USD", transaction.Legs[0].Currency)
+	require.False(t, transaction.Legs[0].CounterpartyWalletID.Valid)
+
+	_, err = s.DepositMoney(ctx, storage.Deposit{WalletID: walletID, Value: 1, Currency: "USD", IdempotencyKey: key})
+	requireIs(t, err, storage.ErrDuplicate)
+	requireBalance(t, s, walletID, 1050)
+
+	_, err = s.DepositMoney(ctx, storage.Deposit{WalletID: -1, Value: 1, Currency: "USD", IdempotencyKey: key})
+	requireIs(t, err, storage.ErrWalletNotFound)
+
+	_, err = s.GetTransaction(ctx, -1)
+	requireIs(t, err, storage.ErrTransactionNotFound)
+}
+
+func testWithdrawal(t *testing.T, s Storage) {
+	ctx := context.Background()
+	walletID := addWallet(t, s, "USD")
+	deposit(t, s, walletID, "USD", 1000)
+
+	withdrawal := storage.Withdrawal{
+		WalletID: walletID, Value: 1001, Currency: "USD", Destination: "iban:DE89370400440532013000",
+		IdempotencyKey: uuid.New().String(),
+	}
+	_, err := s.WithdrawMoney(ctx, withdrawal)
+	requireIs(t, err, storage.ErrInsufficientFunds)
+	requireBalance(t, s, walletID, 1000)
+
+	withdrawal.Value = 1000
+	transactionID, err := s.WithdrawMoney(ctx, withdrawal)
+	require.NoError(t, err)
+	requireBalance(t, s, walletID, 0)
+
+	transaction, err := s.GetTransaction(ctx, transactionID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionWithdrawal, transaction.Type)
+	require.Len(t, transaction.Legs, 1)
+	require.Equal(t, storage.Direction(1), transaction.Legs[0].Direction)
+}
+
+func testTransfer(t *testing.T, s Storage) {
+	ctx := context.Background()
+	fromWalletID := addWallet(t, s, "USD")
+	toWalletID := addWallet(t, s, "USD")
+	deposit(t, s, fromWalletID, "USD", 1000)
+
+	transfer := storage.Transfer{
+		FromWalletID: fromWalletID, ToWalletID: toWalletID, Value: 1001, Currency: "USD", DestinationValue: 1001,
+		IdempotencyKey: uuid.New().String(),
+	}
+	_, err := s.TransferMoney(ctx, transfer)
+	requireIs(t, err, storage.ErrInsufficientFunds)
+
+	transfer.ToWalletID = -1
+	transfer.Value, transfer.DestinationValue = 400, 400
+	_, err = s.TransferMoney(ctx, transfer)
+	requireIs(t, err, storage.ErrWalletNotFound)
+	requireBalance(t, s, fromWalletID, 1000)
+
+	transfer.ToWalletID = toWalletID
+	transactionID, err := s.TransferMoney(ctx, transfer)
+	require.NoError(t, err)
+	requireBalance(t, s, fromWalletID, 600)
+	requireBalance(t, s, toWalletID, 400)
+
+	transaction, err := s.GetTransaction(ctx, transactionID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionTransfer, transaction.Type)
+	require.Len(t, transaction.Legs, 2)
+	require.Equal(t, fromWalletID, transaction.Legs[0].WalletID)
+	require.Equal(t, toWalletID, transaction.Legs[0].CounterpartyWalletID.Int64)
+	require.Equal(t, toWalletID, transaction.Legs[1].WalletID)
+	require.Equal(t, fromWalletID, transaction.Legs[1].CounterpartyWalletID.Int64)
+
+	_, err = s.TransferMoney(ctx, transfer)
+	requireIs(t, err, storage.ErrDuplicate)
+	requireBalance(t, s, fromWalletID, 600)
+}
+
+func testOperations(t *testing.T, s Storage) {
+	ctx := context.Background()
+	walletID := addWallet(t, s, "USD")
+	for i := 1; i <= 3; i++ {
+		deposit(t, s, walletID, "USD", int64(i*100))
+	}
+	_, err := s.WithdrawMoney(ctx, storage.Withdrawal{
+		WalletID: walletID, Value: 50, Currency: "USD", IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+
+	operations, err := s.GetOperations(ctx, storage.Filter{WalletID: walletID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, operations, 4)
+	for i := 1; i < len(operations); i++ {
+		require.False(t, operations[i].CreatedAt.Before(operations[i-1].CreatedAt))
+	}
+
+	direction := storage.Direction(1)
+	withdrawals, err := s.GetOperations(ctx, storage.Filter{WalletID: walletID, Direction: &direction, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	require.Equal(t, int64(50), withdrawals[0].Value)
+
+	page, err := s.GetOperations(ctx, storage.Filter{WalletID: walletID, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, operations[:2], page)
+
+	last := page[len(page)-1]
+	page, err = s.GetOperations(ctx, storage.Filter{
+		WalletID: walletID,
+		After:    &storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, operations[2:], page)
+
+	// dates are in UTC whatever the time zone of the storage
+	day := operations[0].CreatedAt.UTC().Format("2006-01-02")
+	page, err = s.GetOperations(ctx, storage.Filter{WalletID: walletID, From: day, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, operations, page)
+	require.Equal(t, day, page[0].Date)
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	page, err = s.GetOperations(ctx, storage.Filter{WalletID: walletID, From: tomorrow, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, page)
+}
+
+func testConcurrentTransfers(t *testing.T, s Storage) {
+	ctx := context.Background()
+	walletIDs := []int64{addWallet(t, s, "USD"), addWallet(t, s, "USD")}
+	for _, walletID := range walletIDs {
+		deposit(t, s, walletID, "USD", 500)
+	}
+
+	// every transfer takes 100, so some of them must fail when their wallet is empty
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := walletIDs[i%2], walletIDs[(i+1)%2]
+			_, errs[i] = s.TransferMoney(ctx, storage.Transfer{
+				FromWalletID: from, ToWalletID: to, Value: 100, Currency: "USD", DestinationValue: 100,
+				IdempotencyKey: uuid.New().String(),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			requireIs(t, err, storage.ErrInsufficientFunds)
+		}
+	}
+
+	var total int64
+	for _, walletID := range walletIDs {
+		wallet, err := s.GetWallet(ctx, walletID)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, wallet.Value, int64(0))
+		total += wallet.Value
+	}
+	require.Equal(t, int64(1000), total)
+}
+
+func testQuotes(t *testing.T, s Storage) {
+	ctx := context.Background()
+	quote := storage.Quote{
+		ID: uuid.New().String(), FromCurrency: "USD", ToCurrency: "JPY", Rate: "145.12",
+		ExpiresAt: time.Now().Add(time.Minute).Truncate(time.Second),
+	}
+	require.NoError(t, s.AddQuote(ctx, quote))
+
+	saved, err := s.GetQuote(ctx, quote.ID)
+	require.NoError(t, err)
+	require.Equal(t, quote.Rate, saved.Rate)
+	require.Equal(t, quote.FromCurrency, saved.FromCurrency)
+	require.Equal(t, quote.ToCurrency, saved.ToCurrency)
+	require.True(t, quote.ExpiresAt.Equal(saved.ExpiresAt))
+
+	requireIs(t, s.AddQuote(ctx, quote), storage.ErrDuplicate)
+
+	_, err = s.GetQuote(ctx, uuid.New().String())
+	requireIs(t, err, storage.ErrQuoteNotFound)
+}
+
+func testQuoteUsedByOneTransfer(t *testing.T, s Storage) {
+	ctx := context.Background()
+	fromWalletID := addWallet(t, s, "USD")
+	toWalletID := addWallet(t, s, "JPY")
+	deposit(t, s, fromWalletID, "USD", 1000)
+
+	quote := storage.Quote{
+		ID: uuid.New().String(), FromCurrency: "USD", ToCurrency: "JPY", Rate: "145.12",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	require.NoError(t, s.AddQuote(ctx, quote))
+
+	transfer := storage.Transfer{
+		FromWalletID: fromWalletID, ToWalletID: toWalletID, Value: 100, Currency: "USD", DestinationValue: 145,
+		Exchange:       &storage.Exchange{Rate: "145.12", SourceCurrency: "USD", DestinationCurrency: "JPY", QuoteID: quote.ID},
+		IdempotencyKey: uuid.New().String(),
+	}
+	transactionID, err := s.TransferMoney(ctx, transfer)
+	require.NoError(t, err)
+
+	saved, err := s.GetQuote(ctx, quote.ID)
+	require.NoError(t, err)
+	require.Equal(t, transactionID, saved.TransactionID.Int64)
+
+	transfer.IdempotencyKey = uuid.New().String()
+	_, err = s.TransferMoney(ctx, transfer)
+	requireIs(t, err, storage.ErrQuoteUsed)
+	requireBalance(t, s, fromWalletID, 900)
+
+	transfer.Exchange.QuoteID = uuid.New().String()
+	_, err = s.TransferMoney(ctx, transfer)
+	requireIs(t, err, storage.ErrQuoteNotFound)
+	requireBalance(t, s, fromWalletID, 900)
+}
+
+func testHolds(t *testing.T, s Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	payerID := addWallet(t, s, "USD")
+	merchantID := addWallet(t, s, "USD")
+	deposit(t, s, payerID, "USD", 1000)
+
+	authorization := storage.Authorization{
+		FromWalletID: payerID, ToWalletID: merchantID, Value: 600, Currency: "USD",
+		ExpiresAt: now.Add(time.Hour), IdempotencyKey: uuid.New().String(),
+	}
+	hold, err := s.AuthorizeHold(ctx, authorization)
+	require.NoError(t, err)
+	require.Equal(t, storage.HoldAuthorized, hold.Status)
+
+	_, err = s.AuthorizeHold(ctx, authorization)
+	requireIs(t, err, storage.ErrDuplicate)
+
+	payer, err := s.GetWallet(ctx, payerID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), payer.Value)
+	require.Equal(t, int64(600), payer.Held)
+
+	// held money can not be spent twice
+	_, err = s.WithdrawMoney(ctx, storage.Withdrawal{
+		WalletID: payerID, Value: 401, Currency: "USD", IdempotencyKey: uuid.New().String(),
+	})
+	requireIs(t, err, storage.ErrInsufficientFunds)
+
+	authorization.IdempotencyKey, authorization.Value = uuid.New().String(), 401
+	_, err = s.AuthorizeHold(ctx, authorization)
+	requireIs(t, err, storage.ErrInsufficientFunds)
+
+	_, err = s.CaptureHold(ctx, storage.Capture{HoldID: hold.ID, Value: 601, Now: now})
+	requireIs(t, err, storage.ErrCaptureExceedsHold)
+
+	hold, err = s.CaptureHold(ctx, storage.Capture{HoldID: hold.ID, Value: 250, Now: now})
+	require.NoError(t, err)
+	require.Equal(t, storage.HoldCaptured, hold.Status)
+	require.Equal(t, int64(250), hold.CapturedValue)
+	requireBalance(t, s, payerID, 750)
+	requireBalance(t, s, merchantID, 250)
+
+	payer, err = s.GetWallet(ctx, payerID)
+	require.NoError(t, err)
+	require.Zero(t, payer.Held)
+
+	transaction, err := s.GetTransaction(ctx, hold.TransactionID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionAuthorization, transaction.Type)
+	require.Equal(t, storage.TransactionCaptured, transaction.Status)
+	require.Len(t, transaction.Legs, 2)
+
+	_, err = s.CaptureHold(ctx, storage.Capture{HoldID: hold.ID, Now: now})
+	requireIs(t, err, storage.ErrHoldNotAuthorized)
+
+	_, err = s.VoidHold(ctx, hold.ID, now)
+	requireIs(t, err, storage.ErrHoldNotAuthorized)
+
+	_, err = s.VoidHold(ctx, -1, now)
+	requireIs(t, err, storage.ErrHoldNotFound)
+
+	authorization.IdempotencyKey, authorization.Value = uuid.New().String(), 300
+	hold, err = s.AuthorizeHold(ctx, authorization)
+	require.NoError(t, err)
+
+	hold, err = s.VoidHold(ctx, hold.ID, now)
+	require.NoError(t, err)
+	require.Equal(t, storage.HoldVoided, hold.Status)
+
+	saved, err := s.GetHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.HoldVoided, saved.Status)
+
+	payer, err = s.GetWallet(ctx, payerID)
+	require.NoError(t, err)
+	require.Equal(t, int64(750), payer.Value)
+	require.Zero(t, payer.Held)
+}
+
+func testHoldKeyUsedByTransfer(t *testing.T, s Storage) {
+	ctx := context.Background()
+	payerID := addWallet(t, s, "USD")
+	merchantID := addWallet(t, s, "USD")
+	deposit(t, s, payerID, "USD", 1000)
+
+	// operations and holds have their own keys, so a transfer before the capture may use the key of the hold
+	key := uuid.New().String()
+	_, err := s.TransferMoney(ctx, storage.Transfer{
+		FromWalletID: payerID, ToWalletID: merchantID, Value: 100, Currency: "USD", DestinationValue: 100,
+		IdempotencyKey: key,
+	})
+	require.NoError(t, err)
+
+	hold, err := s.AuthorizeHold(ctx, storage.Authorization{
+		FromWalletID: payerID, ToWalletID: merchantID, Value: 300, Currency: "USD",
+		ExpiresAt: time.Now().Add(time.Hour), IdempotencyKey: key,
+	})
+	require.NoError(t, err)
+
+	hold, err = s.CaptureHold(ctx, storage.Capture{HoldID: hold.ID, Now: time.Now()})
+	require.NoError(t, err)
+	require.Equal(t, storage.HoldCaptured, hold.Status)
+	requireBalance(t, s, payerID, 600)
+	requireBalance(t, s, merchantID, 400)
+}
+
+func testHoldExpiry(t *testing.T, s Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	payerID := addWallet(t, s, "USD")
+	merchantID := addWallet(t, s, "USD")
+	deposit(t, s, payerID, "USD", 1000)
+
+	hold, err := s.AuthorizeHold(ctx, storage.Authorization{
+		FromWalletID: payerID, ToWalletID: merchantID, Value: 600, Currency: "USD",
+		ExpiresAt: now.Add(time.Minute), IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+
+	// an expired hold can not be captured even before it is swept
+	_, err = s.CaptureHold(ctx, storage.Capture{HoldID: hold.ID, Now: now.Add(time.Minute)})
+	requireIs(t, err, storage.ErrHoldNotAuthorized)
+
+	expired, err := s.ExpireHolds(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, expired, int64(1))
+
+	hold, err = s.GetHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, storage.HoldExpired, hold.Status)
+
+	payer, err := s.GetWallet(ctx, payerID)
+	require.NoError(t, err)
+	require.Zero(t, payer.Held)
+
+	transaction, err := s.GetTransaction(ctx, hold.TransactionID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionExpired, transaction.Status)
+}
+
+func testRefunds(t *testing.T, s Storage) {
+	ctx := context.Background()
+	payerID := addWallet(t, s, "USD")
+	merchantID := addWallet(t, s, "USD")
+	depositID := deposit(t, s, payerID, "USD", 1000)
+
+	paymentID, err := s.TransferMoney(ctx, storage.Transfer{
+		FromWalletID: payerID, ToWalletID: merchantID, Value: 1000, Currency: "USD", DestinationValue: 1000,
+		IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.RefundTransaction(ctx, storage.Refund{TransactionID: depositID, IdempotencyKey: uuid.New().String()})
+	requireIs(t, err, storage.ErrNotRefundable)
+
+	_, err = s.RefundTransaction(ctx, storage.Refund{TransactionID: -1, IdempotencyKey: uuid.New().String()})
+	requireIs(t, err, storage.ErrTransactionNotFound)
+
+	refundID, err := s.RefundTransaction(ctx, storage.Refund{
+		TransactionID: paymentID, Value: 400, Initiator: "support", IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+	requireBalance(t, s, payerID, 400)
+	requireBalance(t, s, merchantID, 600)
+
+	refund, err := s.GetTransaction(ctx, refundID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionRefund, refund.Type)
+	require.Equal(t, paymentID, refund.OriginalTransactionID.Int64)
+	require.Len(t, refund.Legs, 2)
+
+	payment, err := s.GetTransaction(ctx, paymentID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionPartiallyRefunded, payment.Status)
+	require.Equal(t, int64(400), payment.RefundedValue)
+
+	_, err = s.RefundTransaction(ctx, storage.Refund{TransactionID: paymentID, Value: 601, IdempotencyKey: uuid.New().String()})
+	requireIs(t, err, storage.ErrRefundExceedsTransaction)
+
+	_, err = s.RefundTransaction(ctx, storage.Refund{TransactionID: paymentID, IdempotencyKey: uuid.New().String()})
+	require.NoError(t, err)
+	requireBalance(t, s, payerID, 1000)
+	requireBalance(t, s, merchantID, 0)
+
+	payment, err = s.GetTransaction(ctx, paymentID)
+	require.NoError(t, err)
+	require.Equal(t, storage.TransactionRefunded, payment.Status)
+
+	operations, err := s.GetOperations(ctx, storage.Filter{WalletID: payerID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, operations, 4)
+	require.False(t, operations[1].OriginalTransactionID.Valid)
+	require.Equal(t, paymentID, operations[2].OriginalTransactionID.Int64)
+}
+
+func testIdempotencyKeys(t *testing.T, s Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	key := storage.IdempotencyKey{
+		Endpoint: "test", Scope: "1", Key: uuid.New().String(), Fingerprint: "abc",
+		ReservedUntil: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour),
+	}
+	_, reserved, err := s.ReserveIdempotencyKey(ctx, key, now)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// the same key in another scope is another request
+	other := key
+	other.Scope, other.Fingerprint = "2", "def"
+	_, reserved, err = s.ReserveIdempotencyKey(ctx, other, now)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	saved, reserved, err := s.ReserveIdempotencyKey(ctx, key, now)
+	require.NoError(t, err)
+	require.False(t, reserved)
+	require.False(t, saved.StatusCode.Valid)
+
+	// a pending key of a lost request is reserved again by a retry
+	_, reserved, err = s.ReserveIdempotencyKey(ctx, key, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// a released key can be reserved again
+	require.NoError(t, s.ReleaseIdempotencyKey(ctx, key))
+	_, reserved, err = s.ReserveIdempotencyKey(ctx, key, now)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	key.StatusCode.Int32, key.StatusCode.Valid = 200, true
+	key.ContentType.String, key.ContentType.Valid = "application/json", true
+	key.ResponseBody = []byte(`{"wallet_id":1}`)
+	require.NoError(t, s.CompleteIdempotencyKey(ctx, key))
+
+	// a completed key is not released and replays its response
+	require.NoError(t, s.ReleaseIdempotencyKey(ctx, key))
+	saved, reserved, err = s.ReserveIdempotencyKey(ctx, key, now)
+	require.NoError(t, err)
+	require.False(t, reserved)
+	require.Equal(t, int32(200), saved.StatusCode.Int32)
+	require.Equal(t, "application/json", saved.ContentType.String)
+	require.Equal(t, key.ResponseBody, saved.ResponseBody)
+	require.Equal(t, "abc", saved.Fingerprint)
+
+	// a completed key is kept after its reservation
+	_, reserved, err = s.ReserveIdempotencyKey(ctx, key, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	// an expired key is replaced
+	_, reserved, err = s.ReserveIdempotencyKey(ctx, key, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// expired keys are deleted without a retry
+	deleted, err := s.DeleteExpiredIdempotencyKeys(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+	_, reserved, err = s.ReserveIdempotencyKey(ctx, other, now)
+	require.NoError(t, err)
+	require.True(t, reserved, "expired key of the other scope is deleted")
+}
+
+func addWallet(t *testing.T, s Storage, currency string) int64 {
+	t.Helper()
+	walletID, err := s.AddWallet(context.Background(), storage.Wallet{IdempotencyKey: uuid.New().String(), Currency: currency})
+	require.NoError(t, err)
+	return walletID
+}
+
+func deposit(t *testing.T, s Storage, walletID int64, currency string, value int64) int64 {
+	t.Helper()
+	transactionID, err := s.DepositMoney(context.Background(), storage.Deposit{
+		WalletID: walletID, Value: value, Currency: currency, IdempotencyKey: uuid.New().String(),
+	})
+	require.NoError(t, err)
+	return transactionID
+}
+
+func requireBalance(t *testing.T, s Storage, walletID, value int64) {
+	t.Helper()
+	wallet, err := s.GetWallet(context.Background(), walletID)
+	require.NoError(t, err)
+	require.Equal(t, value, wallet.Value)
+}
+
+func requireIs(t *testing.T, err, target error) {
+	t.Helper()
+	require.True(t, errors.Is(err, target), "got error %v, want %v", err, target)
+}
@@ -7,8 +7,10 @@ package wallet
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
+	money "payment-system/internal/money"
 	storage "payment-system/internal/storage"
 	reflect "reflect"
+	time "time"
 )
 
 // MockwalletStorage is a mock of walletStorage interface
@@ -49,12 +51,28 @@ func (mr *MockwalletStorageMockRecorder) AddWallet(ctx, wallet interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWallet", reflect.TypeOf((*MockwalletStorage)(nil).AddWallet), ctx, wallet)
 }
 
+// GetWallet mocks base method
+func (m *MockwalletStorage) GetWallet(ctx context.Context, walletID int64) (storage.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWallet", ctx, walletID)
+	ret0, _ := ret[0].(storage.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWallet indicates an expected call of GetWallet
+func (mr *MockwalletStorageMockRecorder) GetWallet(ctx, walletID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWallet", reflect.TypeOf((*MockwalletStorage)(nil).GetWallet), ctx, walletID)
+}
+
 // DepositMoney mocks base method
-func (m *MockwalletStorage) DepositMoney(ctx context.Context, deposit storage.Deposit) error {
+func (m *MockwalletStorage) DepositMoney(ctx context.Context, deposit storage.Deposit) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DepositMoney", ctx, deposit)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DepositMoney indicates an expected call of DepositMoney
@@ -63,12 +81,28 @@ func (mr *MockwalletStorageMockRecorder) DepositMoney(ctx, deposit interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DepositMoney", reflect.TypeOf((*MockwalletStorage)(nil).DepositMoney), ctx, deposit)
 }
 
+// WithdrawMoney mocks base method
+func (m *MockwalletStorage) WithdrawMoney(ctx context.Context, info storage.Withdrawal) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithdrawMoney", ctx, info)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WithdrawMoney indicates an expected call of WithdrawMoney
+func (mr *MockwalletStorageMockRecorder) WithdrawMoney(ctx, info interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithdrawMoney", reflect.TypeOf((*MockwalletStorage)(nil).WithdrawMoney), ctx, info)
+}
+
 // TransferMoney mocks base method
-func (m *MockwalletStorage) TransferMoney(ctx context.Context, info storage.Transfer) error {
+func (m *MockwalletStorage) TransferMoney(ctx context.Context, info storage.Transfer) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "TransferMoney", ctx, info)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // TransferMoney indicates an expected call of TransferMoney
@@ -77,6 +111,21 @@ func (mr *MockwalletStorageMockRecorder) TransferMoney(ctx, info interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferMoney", reflect.TypeOf((*MockwalletStorage)(nil).TransferMoney), ctx, info)
 }
 
+// GetTransaction mocks base method
+func (m *MockwalletStorage) GetTransaction(ctx context.Context, transactionID int64) (storage.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, transactionID)
+	ret0, _ := ret[0].(storage.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransaction indicates an expected call of GetTransaction
+func (mr *MockwalletStorageMockRecorder) GetTransaction(ctx, transactionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockwalletStorage)(nil).GetTransaction), ctx, transactionID)
+}
+
 // GetOperations mocks base method
 func (m *MockwalletStorage) GetOperations(ctx context.Context, filter storage.Filter) ([]storage.Operation, error) {
 	m.ctrl.T.Helper()
@@ -91,3 +140,195 @@ func (mr *MockwalletStorageMockRecorder) GetOperations(ctx, filter interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperations", reflect.TypeOf((*MockwalletStorage)(nil).GetOperations), ctx, filter)
 }
+
+// AddQuote mocks base method
+func (m *MockwalletStorage) AddQuote(ctx context.Context, quote storage.Quote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddQuote", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddQuote indicates an expected call of AddQuote
+func (mr *MockwalletStorageMockRecorder) AddQuote(ctx, quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddQuote", reflect.TypeOf((*MockwalletStorage)(nil).AddQuote), ctx, quote)
+}
+
+// GetQuote mocks base method
+func (m *MockwalletStorage) GetQuote(ctx context.Context, quoteID string) (storage.Quote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuote", ctx, quoteID)
+	ret0, _ := ret[0].(storage.Quote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuote indicates an expected call of GetQuote
+func (mr *MockwalletStorageMockRecorder) GetQuote(ctx, quoteID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuote", reflect.TypeOf((*MockwalletStorage)(nil).GetQuote), ctx, quoteID)
+}
+
+// AuthorizeHold mocks base method
+func (m *MockwalletStorage) AuthorizeHold(ctx context.Context, info storage.Authorization) (storage.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeHold", ctx, info)
+	ret0, _ := ret[0].(storage.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeHold indicates an expected call of AuthorizeHold
+func (mr *MockwalletStorageMockRecorder) AuthorizeHold(ctx, info interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeHold", reflect.TypeOf((*MockwalletStorage)(nil).AuthorizeHold), ctx, info)
+}
+
+// CaptureHold mocks base method
+func (m *MockwalletStorage) CaptureHold(ctx context.Context, info storage.Capture) (storage.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, info)
+	ret0, _ := ret[0].(storage.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold
+func (mr *MockwalletStorageMockRecorder) CaptureHold(ctx, info interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockwalletStorage)(nil).CaptureHold), ctx, info)
+}
+
+// GetHold mocks base method
+func (m *MockwalletStorage) GetHold(ctx context.Context, holdID int64) (storage.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, holdID)
+	ret0, _ := ret[0].(storage.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold
+func (mr *MockwalletStorageMockRecorder) GetHold(ctx, holdID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockwalletStorage)(nil).GetHold), ctx, holdID)
+}
+
+// VoidHold mocks base method
+func (m *MockwalletStorage) VoidHold(ctx context.Context, holdID int64, now time.Time) (storage.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VoidHold", ctx, holdID, now)
+	ret0, _ := ret[0].(storage.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VoidHold indicates an expected call of VoidHold
+func (mr *MockwalletStorageMockRecorder) VoidHold(ctx, holdID, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoidHold", reflect.TypeOf((*MockwalletStorage)(nil).VoidHold), ctx, holdID, now)
+}
+
+// ExpireHolds mocks base method
+func (m *MockwalletStorage) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds
+func (mr *MockwalletStorageMockRecorder) ExpireHolds(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockwalletStorage)(nil).ExpireHolds), ctx, now)
+}
+
+// RefundTransaction mocks base method
+func (m *MockwalletStorage) RefundTransaction(ctx context.Context, info storage.Refund) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundTransaction", ctx, info)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundTransaction indicates an expected call of RefundTransaction
+func (mr *MockwalletStorageMockRecorder) RefundTransaction(ctx, info interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundTransaction", reflect.TypeOf((*MockwalletStorage)(nil).RefundTransaction), ctx, info)
+}
+
+// MockOperationObserver is a mock of OperationObserver interface
+type MockOperationObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperationObserverMockRecorder
+}
+
+// MockOperationObserverMockRecorder is the mock recorder for MockOperationObserver
+type MockOperationObserverMockRecorder struct {
+	mock *MockOperationObserver
+}
+
+// NewMockOperationObserver creates a new mock instance
+func NewMockOperationObserver(ctrl *gomock.Controller) *MockOperationObserver {
+	mock := &MockOperationObserver{ctrl: ctrl}
+	mock.recorder = &MockOperationObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOperationObserver) EXPECT() *MockOperationObserverMockRecorder {
+	return m.recorder
+}
+
+// ObserveOperation mocks base method
+func (m *MockOperationObserver) ObserveOperation(operation string, currency money.Currency, amount money.Amount, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObserveOperation", operation, currency, amount, err)
+}
+
+// ObserveOperation indicates an expected call of ObserveOperation
+func (mr *MockOperationObserverMockRecorder) ObserveOperation(operation, currency, amount, err interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveOperation", reflect.TypeOf((*MockOperationObserver)(nil).ObserveOperation), operation, currency, amount, err)
+}
+
+// MockFXRateProvider is a mock of FXRateProvider interface
+type MockFXRateProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockFXRateProviderMockRecorder
+}
+
+// MockFXRateProviderMockRecorder is the mock recorder for MockFXRateProvider
+type MockFXRateProviderMockRecorder struct {
+	mock *MockFXRateProvider
+}
+
+// NewMockFXRateProvider creates a new mock instance
+func NewMockFXRateProvider(ctrl *gomock.Controller) *MockFXRateProvider {
+	mock := &MockFXRateProvider{ctrl: ctrl}
+	mock.recorder = &MockFXRateProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockFXRateProvider) EXPECT() *MockFXRateProviderMockRecorder {
+	return m.recorder
+}
+
+// Rate mocks base method
+func (m *MockFXRateProvider) Rate(ctx context.Context, from, to money.Currency) (money.Amount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rate", ctx, from, to)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rate indicates an expected call of Rate
+func (mr *MockFXRateProviderMockRecorder) Rate(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rate", reflect.TypeOf((*MockFXRateProvider)(nil).Rate), ctx, from, to)
+}
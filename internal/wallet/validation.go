@@ -0,0 +1,115 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"payment-system/internal/money"
+	"payment-system/internal/storage"
+)
+
+var (
+	// ErrValidation matches every ValidationError.
+	ErrValidation          = errors.New("validation failed")
+	ErrSameWallet          = errors.New("source and destination wallets are the same")
+	ErrAmountLimitExceeded = errors.New("amount exceeds limit")
+)
+
+// Violation is a single invalid field of a request. Field is named as in the API.
+type Violation struct {
+	Field string
+	Err   error
+}
+
+// ValidationError reports all violations of a request together.
+// errors.Is matches ErrValidation and the error of any violation, e.g. ErrWalletNotFound.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", v.Field, v.Err))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	if target == ErrValidation {
+		return true
+	}
+
+	for _, v := range e.Violations {
+		if errors.Is(v.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validation collects violations of a single request.
+type validation struct {
+	violations []Violation
+}
+
+func (v *validation) add(field string, err error) {
+	v.violations = append(v.violations, Violation{Field: field, Err: err})
+}
+
+func (v *validation) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Violations: v.violations}
+}
+
+// checkAmount requires a positive amount.
+func checkAmount(v *validation, field string, amount money.Amount) {
+	if amount.Sign() <= 0 {
+		v.add(field, fmt.Errorf("%w: %s is not positive", ErrInvalidAmount, amount))
+	}
+}
+
+// checkLimit requires an amount within the limit of its currency, currencies without a limit are not limited.
+func (s *Service) checkLimit(v *validation, field string, amount money.Amount, currency money.Currency) {
+	if limit, ok := s.maxAmounts[currency]; ok && amount.Cmp(limit) > 0 {
+		v.add(field, fmt.Errorf("%w: %s %s is over %s %s", ErrAmountLimitExceeded, amount, currency, limit, currency))
+	}
+}
+
+// checkCurrency requires the optional currency of a request to match the wallet currency.
+func checkCurrency(v *validation, requested, currency money.Currency, operation string) {
+	if requested != "" && requested != currency {
+		v.add("currency", fmt.Errorf("%w: %s in %s for %s wallet", ErrCurrencyMismatch, operation, requested, currency))
+	}
+}
+
+// findWallet reports a missing wallet as a violation, other storage errors are returned as is.
+func (s *Service) findWallet(ctx context.Context, v *validation, field string, walletID int64) (storage.Wallet, bool, error) {
+	w, err := s.storage.GetWallet(ctx, walletID)
+	if errors.Is(err, ErrWalletNotFound) {
+		v.add(field, fmt.Errorf("%w: %d", ErrWalletNotFound, walletID))
+		return storage.Wallet{}, false, nil
+	}
+	if err != nil {
+		return storage.Wallet{}, false, fmt.Errorf("getting wallet from storage: %w", err)
+	}
+
+	return w, true, nil
+}
+
+// minorUnits reports an amount which does not fit the currency as a violation.
+func minorUnits(v *validation, field string, amount money.Amount, currency money.Currency) int64 {
+	units, err := toMinorUnits(amount, currency)
+	if err != nil {
+		v.add(field, err)
+	}
+
+	return units
+}
@@ -3,59 +3,301 @@ package wallet
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+
+	"payment-system/internal/logging"
+	"payment-system/internal/money"
+	"payment-system/internal/spanlog"
 	"payment-system/internal/storage"
 )
 
+// Storage and money errors are propagated as is, so callers can match them with errors.Is.
+var (
+	ErrDuplicate                = storage.ErrDuplicate
+	ErrWalletNotFound           = storage.ErrWalletNotFound
+	ErrInsufficientFunds        = storage.ErrInsufficientFunds
+	ErrInvalidAmount            = money.ErrInvalidAmount
+	ErrCurrencyMismatch         = errors.New("currency mismatch")
+	ErrConversionUnsupported    = errors.New("currency conversion is not supported")
+	ErrQuoteNotFound            = storage.ErrQuoteNotFound
+	ErrQuoteExpired             = errors.New("quote expired")
+	ErrQuoteUsed                = storage.ErrQuoteUsed
+	ErrInvalidCursor            = errors.New("invalid cursor")
+	ErrTransactionNotFound      = storage.ErrTransactionNotFound
+	ErrHoldNotFound             = storage.ErrHoldNotFound
+	ErrHoldNotAuthorized        = storage.ErrHoldNotAuthorized
+	ErrCaptureExceedsHold       = storage.ErrCaptureExceedsHold
+	ErrNotRefundable            = storage.ErrNotRefundable
+	ErrRefundExceedsTransaction = storage.ErrRefundExceedsTransaction
+	ErrConcurrentUpdate         = storage.ErrConcurrentUpdate
+)
+
+const (
+	// defaultInitiator is recorded on transactions when a caller does not introduce itself.
+	defaultInitiator       = "api"
+	defaultQuoteTTL        = 30 * time.Second
+	defaultHoldTTL         = 7 * 24 * time.Hour
+	defaultOperationsLimit = 100
+	maxOperationsLimit     = 1000
+)
+
 type Wallet struct {
 	IdempotencyKey string
+	Currency       money.Currency
 }
 
+// Deposit is credited in the wallet currency.
+// Currency is optional, when it is set it must match the wallet currency.
 type Deposit struct {
 	WalletID       int64
-	Value          float64
+	Value          money.Amount
+	Currency       money.Currency
+	Initiator      string
+	IdempotencyKey string
+}
+
+// Withdrawal pays money out of the wallet to the external Destination, e.g. a bank account.
+// Currency is optional, when it is set it must match the wallet currency.
+type Withdrawal struct {
+	WalletID       int64
+	Value          money.Amount
+	Currency       money.Currency
+	Destination    string
+	Initiator      string
 	IdempotencyKey string
 }
 
+// Transfer value is in the currency of the source wallet.
+// Currency is optional, when it is set it must match the source wallet currency.
+// Wallets with different currencies are rejected unless ConvertCurrency is set,
+// then the rate of the locked quote QuoteID is used, or the current rate when it is empty.
+// A locked quote is used by one transfer only.
 type Transfer struct {
+	FromWalletID    int64
+	ToWalletID      int64
+	Value           money.Amount
+	Currency        money.Currency
+	ConvertCurrency bool
+	QuoteID         string
+	Initiator       string
+	IdempotencyKey  string
+}
+
+// Authorization reserves money of the source wallet for a payment to the destination wallet.
+// Both wallets must have the same currency. Currency is optional, when it is set it must match it.
+type Authorization struct {
 	FromWalletID   int64
 	ToWalletID     int64
-	Value          float64
+	Value          money.Amount
+	Currency       money.Currency
+	Initiator      string
+	IdempotencyKey string
+}
+
+// Capture pays Value of the hold, zero Value captures the whole hold. The rest of the hold is released.
+type Capture struct {
+	HoldID int64
+	Value  money.Amount
+}
+
+// Refund returns Value of the original transfer or captured authorization from its destination wallet
+// back to its source wallet. Value is in the currency of the destination wallet, zero Value refunds
+// everything which was not refunded yet.
+type Refund struct {
+	TransactionID  int64
+	Value          money.Amount
+	Initiator      string
 	IdempotencyKey string
 }
 
+// Hold reserves Value until it is captured, voided or expired at ExpiresAt.
+type Hold struct {
+	ID            int64
+	TransactionID int64
+	FromWalletID  int64
+	ToWalletID    int64
+	Value         money.Amount
+	CapturedValue money.Amount
+	Currency      money.Currency
+	Status        string
+	ExpiresAt     time.Time
+}
+
+// Quote locks an exchange rate for one transfer until ExpiresAt.
+type Quote struct {
+	ID        string
+	From      money.Currency
+	To        money.Currency
+	Rate      money.Amount
+	ExpiresAt time.Time
+}
+
+// Balance is the current state of a wallet. Available is the part of Value
+// which can be spent right now.
+type Balance struct {
+	WalletID  int64
+	Value     money.Amount
+	Available money.Amount
+	Currency  money.Currency
+	UpdatedAt time.Time
+}
+
+// Filter selects operations of the wallet between From and To dates inclusive.
+// Empty bounds are open, nil Direction selects both directions.
+// Cursor continues the listing from NextCursor of the previous page.
 type Filter struct {
 	WalletID  int64
-	Date      string
-	Direction int8
+	From      string
+	To        string
+	Direction *int8
+	Limit     int
+	Cursor    string
+}
+
+type OperationsPage struct {
+	Operations []Operation
+	// NextCursor is empty on the last page.
+	NextCursor string
 }
 
+// Operation is a leg of a transaction on the wallet.
+// CounterpartyWalletID is zero for deposits and withdrawals, OriginalTransactionID is set for refunds only.
 type Operation struct {
-	WalletID  int64
-	Value     float64
-	Direction int8
-	Date      string
+	ID                    int64
+	TransactionID         int64
+	OriginalTransactionID int64
+	WalletID              int64
+	CounterpartyWalletID  int64
+	Value                 money.Amount
+	Direction             int8
+	Date                  string
+	CreatedAt             time.Time
+}
+
+// Transaction is a single deposit, withdrawal or transfer with all its legs.
+// OriginalTransactionID is set for refunds, RefundedValue is in the currency of the destination wallet.
+type Transaction struct {
+	ID                    int64
+	Type                  string
+	Status                string
+	Initiator             string
+	OriginalTransactionID int64
+	RefundedValue         money.Amount
+	CreatedAt             time.Time
+	Legs                  []Leg
+}
+
+// Leg value is in the currency of its wallet. CounterpartyWalletID is zero for deposits and withdrawals.
+type Leg struct {
+	OperationID          int64
+	WalletID             int64
+	CounterpartyWalletID int64
+	Value                money.Amount
+	Currency             money.Currency
+	Direction            int8
+	CreatedAt            time.Time
 }
 
 type walletStorage interface {
 	AddWallet(ctx context.Context, wallet storage.Wallet) (int64, error)
-	DepositMoney(ctx context.Context, deposit storage.Deposit) error
-	TransferMoney(ctx context.Context, info storage.Transfer) error
+	GetWallet(ctx context.Context, walletID int64) (storage.Wallet, error)
+	DepositMoney(ctx context.Context, deposit storage.Deposit) (int64, error)
+	WithdrawMoney(ctx context.Context, info storage.Withdrawal) (int64, error)
+	TransferMoney(ctx context.Context, info storage.Transfer) (int64, error)
+	GetTransaction(ctx context.Context, transactionID int64) (storage.Transaction, error)
 	GetOperations(ctx context.Context, filter storage.Filter) ([]storage.Operation, error)
+	AddQuote(ctx context.Context, quote storage.Quote) error
+	GetQuote(ctx context.Context, quoteID string) (storage.Quote, error)
+	AuthorizeHold(ctx context.Context, info storage.Authorization) (storage.Hold, error)
+	CaptureHold(ctx context.Context, info storage.Capture) (storage.Hold, error)
+	GetHold(ctx context.Context, holdID int64) (storage.Hold, error)
+	VoidHold(ctx context.Context, holdID int64, now time.Time) (storage.Hold, error)
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
+	RefundTransaction(ctx context.Context, info storage.Refund) (int64, error)
+}
+
+// OperationObserver is notified of every money move with the wallet currency when it is known, e.g. to export metrics.
+// Zero amount means a full capture or refund.
+type OperationObserver interface {
+	ObserveOperation(operation string, currency money.Currency, amount money.Amount, err error)
+}
+
+// FXRateProvider provides the rate to convert one unit of from currency into to currency.
+type FXRateProvider interface {
+	Rate(ctx context.Context, from, to money.Currency) (money.Amount, error)
 }
 
 type Service struct {
-	storage walletStorage
+	storage  walletStorage
+	rates    FXRateProvider
+	quoteTTL time.Duration
+	holdTTL  time.Duration
+	// maxAmounts limits a single money move by its currency, currencies without an entry are not limited.
+	maxAmounts map[money.Currency]money.Amount
+	observer   OperationObserver
+	now        func() time.Time
+}
+
+type Option func(*Service)
+
+// WithFXRates enables cross-currency transfers with rates of the provider.
+// Locked quotes are valid during quoteTTL.
+func WithFXRates(rates FXRateProvider, quoteTTL time.Duration) Option {
+	return func(s *Service) {
+		s.rates = rates
+		s.quoteTTL = quoteTTL
+	}
 }
 
-func New(storage walletStorage) *Service {
-	return &Service{storage: storage}
+// WithHoldTTL sets how long authorized holds reserve money before they expire.
+func WithHoldTTL(holdTTL time.Duration) Option {
+	return func(s *Service) {
+		s.holdTTL = holdTTL
+	}
+}
+
+// WithMaxAmounts rejects money moves of more than the limit of their currency. A transfer or a hold
+// is limited in the currency of the source wallet, a refund in the currency of the refunded payee.
+func WithMaxAmounts(limits map[money.Currency]money.Amount) Option {
+	return func(s *Service) {
+		s.maxAmounts = limits
+	}
+}
+
+// WithObserver reports deposits, withdrawals, transfers, holds and refunds to the observer.
+func WithObserver(observer OperationObserver) Option {
+	return func(s *Service) {
+		s.observer = observer
+	}
+}
+
+func New(storage walletStorage, options ...Option) *Service {
+	s := &Service{
+		storage:  storage,
+		quoteTTL: defaultQuoteTTL,
+		holdTTL:  defaultHoldTTL,
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
 }
 
-func (s *Service) AddWallet(ctx context.Context, wallet Wallet) (int64, error) {
+func (s *Service) AddWallet(ctx context.Context, wallet Wallet) (_ int64, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.AddWallet")
+	defer span.End(&err)
+
 	w := storage.Wallet{
 		IdempotencyKey: wallet.IdempotencyKey,
+		Currency:       string(wallet.Currency),
 	}
 	walletID, err := s.storage.AddWallet(ctx, w)
 	if err != nil {
@@ -65,62 +307,644 @@ func (s *Service) AddWallet(ctx context.Context, wallet Wallet) (int64, error) {
 	return walletID, nil
 }
 
-func (s *Service) DepositMoney(ctx context.Context, deposit Deposit) error {
+func (s *Service) GetBalance(ctx context.Context, walletID int64) (_ Balance, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.GetBalance", "wallet_id", walletID)
+	defer span.End(&err)
+
+	w, err := s.storage.GetWallet(ctx, walletID)
+	if err != nil {
+		return Balance{}, fmt.Errorf("getting wallet from storage: %w", err)
+	}
+
+	currency := money.Currency(w.Currency)
+	balance := Balance{
+		WalletID:  w.ID,
+		Value:     fromMinorUnits(w.Value, currency),
+		Available: fromMinorUnits(w.Value-w.Held, currency),
+		Currency:  currency,
+		UpdatedAt: w.UpdatedAt,
+	}
+
+	return balance, nil
+}
+
+// DepositMoney returns the id of the created transaction.
+func (s *Service) DepositMoney(ctx context.Context, deposit Deposit) (transactionID int64, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.DepositMoney", "wallet_id", deposit.WalletID)
+	defer span.End(&err)
+
+	var currency money.Currency
+	defer func() {
+		s.observe("deposit", currency, deposit.Value, err)
+	}()
+
+	var v validation
+	checkAmount(&v, "value", deposit.Value)
+	w, found, err := s.findWallet(ctx, &v, "wallet_id", deposit.WalletID)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+	if found {
+		currency = money.Currency(w.Currency)
+		checkCurrency(&v, deposit.Currency, currency, "deposit")
+		s.checkLimit(&v, "value", deposit.Value, currency)
+		value = minorUnits(&v, "value", deposit.Value, currency)
+	}
+	if err := v.err(); err != nil {
+		return 0, err
+	}
+
 	d := storage.Deposit{
 		WalletID:       deposit.WalletID,
-		Value:          dollarsToCents(deposit.Value),
+		Value:          value,
+		Currency:       w.Currency,
+		Initiator:      initiator(deposit.Initiator),
 		IdempotencyKey: deposit.IdempotencyKey,
 	}
-	if err := s.storage.DepositMoney(ctx, d); err != nil {
-		return fmt.Errorf("depositing money into storage: %w", err)
+	transactionID, err = s.storage.DepositMoney(ctx, d)
+	if err != nil {
+		return 0, fmt.Errorf("depositing money into storage: %w", err)
 	}
 
-	return nil
+	return transactionID, nil
 }
 
-func (s *Service) TransferMoney(ctx context.Context, transfer Transfer) error {
+// WithdrawMoney returns the id of the created transaction.
+func (s *Service) WithdrawMoney(ctx context.Context, withdrawal Withdrawal) (transactionID int64, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.WithdrawMoney", "wallet_id", withdrawal.WalletID)
+	defer span.End(&err)
+
+	var currency money.Currency
+	defer func() {
+		s.observe("withdrawal", currency, withdrawal.Value, err)
+	}()
+
+	var v validation
+	checkAmount(&v, "value", withdrawal.Value)
+	w, found, err := s.findWallet(ctx, &v, "wallet_id", withdrawal.WalletID)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+	if found {
+		currency = money.Currency(w.Currency)
+		checkCurrency(&v, withdrawal.Currency, currency, "withdrawal")
+		s.checkLimit(&v, "value", withdrawal.Value, currency)
+		value = minorUnits(&v, "value", withdrawal.Value, currency)
+	}
+	if err := v.err(); err != nil {
+		return 0, err
+	}
+
+	wd := storage.Withdrawal{
+		WalletID:       withdrawal.WalletID,
+		Value:          value,
+		Currency:       w.Currency,
+		Destination:    withdrawal.Destination,
+		Initiator:      initiator(withdrawal.Initiator),
+		IdempotencyKey: withdrawal.IdempotencyKey,
+	}
+	transactionID, err = s.storage.WithdrawMoney(ctx, wd)
+	if err != nil {
+		return 0, fmt.Errorf("withdrawing money from storage: %w", err)
+	}
+
+	return transactionID, nil
+}
+
+// TransferMoney returns the id of the created transaction.
+func (s *Service) TransferMoney(ctx context.Context, transfer Transfer) (transactionID int64, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.TransferMoney", "from_wallet_id", transfer.FromWalletID, "to_wallet_id", transfer.ToWalletID)
+	defer span.End(&err)
+
+	var currency money.Currency
+	defer func() {
+		s.observe("transfer", currency, transfer.Value, err)
+	}()
+
+	var v validation
+	checkAmount(&v, "value", transfer.Value)
+	if transfer.FromWalletID == transfer.ToWalletID {
+		v.add("to_wallet_id", fmt.Errorf("%w: %d", ErrSameWallet, transfer.ToWalletID))
+	}
+
+	from, fromFound, err := s.findWallet(ctx, &v, "from_wallet_id", transfer.FromWalletID)
+	if err != nil {
+		return 0, err
+	}
+
+	to, toFound, err := s.findWallet(ctx, &v, "to_wallet_id", transfer.ToWalletID)
+	if err != nil {
+		return 0, err
+	}
+
+	currency = money.Currency(from.Currency)
+	var value int64
+	if fromFound {
+		checkCurrency(&v, transfer.Currency, currency, "transfer")
+		s.checkLimit(&v, "value", transfer.Value, currency)
+		value = minorUnits(&v, "value", transfer.Value, currency)
+	}
+	if fromFound && toFound && from.Currency != to.Currency && !transfer.ConvertCurrency {
+		v.add("to_wallet_id", fmt.Errorf("%w: transfer from %s wallet to %s wallet", ErrCurrencyMismatch, from.Currency, to.Currency))
+	}
+	if err := v.err(); err != nil {
+		return 0, err
+	}
+
 	t := storage.Transfer{
-		FromWalletID:   transfer.FromWalletID,
-		ToWalletID:     transfer.ToWalletID,
-		Value:          dollarsToCents(transfer.Value),
-		IdempotencyKey: transfer.IdempotencyKey,
+		FromWalletID:     transfer.FromWalletID,
+		ToWalletID:       transfer.ToWalletID,
+		Value:            value,
+		Currency:         from.Currency,
+		DestinationValue: value,
+		Initiator:        initiator(transfer.Initiator),
+		IdempotencyKey:   transfer.IdempotencyKey,
 	}
-	if err := s.storage.TransferMoney(ctx, t); err != nil {
-		return fmt.Errorf("transferring money into storage: %w", err)
+
+	if from.Currency != to.Currency {
+		destinationCurrency := money.Currency(to.Currency)
+		rate, err := s.exchangeRate(ctx, transfer.QuoteID, currency, destinationCurrency)
+		if err != nil {
+			return 0, err
+		}
+
+		t.DestinationValue, err = money.Exchange(value, currency, destinationCurrency, rate)
+		if err != nil {
+			return 0, fmt.Errorf("exchanging %s %s to %s: %w", transfer.Value, currency, destinationCurrency, err)
+		}
+		if t.DestinationValue == 0 {
+			return 0, fmt.Errorf("%w: %s %s is less than a minor unit of %s", ErrInvalidAmount, transfer.Value, currency, destinationCurrency)
+		}
+
+		t.Exchange = &storage.Exchange{
+			Rate:                rate.String(),
+			SourceCurrency:      from.Currency,
+			DestinationCurrency: to.Currency,
+			QuoteID:             transfer.QuoteID,
+		}
+	}
+
+	transactionID, err = s.storage.TransferMoney(ctx, t)
+	if err != nil {
+		return 0, fmt.Errorf("transferring money into storage: %w", err)
+	}
+
+	return transactionID, nil
+}
+
+// GetTransaction returns the transaction with its legs, so money can be traced between wallets.
+func (s *Service) GetTransaction(ctx context.Context, transactionID int64) (_ Transaction, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.GetTransaction", "transaction_id", transactionID)
+	defer span.End(&err)
+
+	t, err := s.storage.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("getting transaction from storage: %w", err)
+	}
+
+	transaction := Transaction{
+		ID:                    t.ID,
+		Type:                  string(t.Type),
+		Status:                string(t.Status),
+		Initiator:             t.Initiator,
+		OriginalTransactionID: t.OriginalTransactionID.Int64,
+		RefundedValue:         fromMinorUnits(t.RefundedValue, payeeCurrency(t)),
+		CreatedAt:             t.CreatedAt,
+		Legs:                  make([]Leg, 0, len(t.Legs)),
+	}
+	for _, l := range t.Legs {
+		currency := money.Currency(l.Currency)
+		leg := Leg{
+			OperationID:          l.OperationID,
+			WalletID:             l.WalletID,
+			CounterpartyWalletID: l.CounterpartyWalletID.Int64,
+			Value:                fromMinorUnits(l.Value, currency),
+			Currency:             currency,
+			Direction:            int8(l.Direction),
+			CreatedAt:            l.CreatedAt,
+		}
+		transaction.Legs = append(transaction.Legs, leg)
 	}
 
-	return nil
+	return transaction, nil
 }
 
-func (s *Service) GetOperations(ctx context.Context, filter Filter) ([]Operation, error) {
+// RefundTransaction returns the id of the refund transaction. Refunds of a transaction are limited
+// by its value altogether, a cross-currency transfer is refunded at its original rate.
+func (s *Service) RefundTransaction(ctx context.Context, refund Refund) (transactionID int64, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.RefundTransaction", "transaction_id", refund.TransactionID)
+	defer span.End(&err)
+
+	var currency money.Currency
+	defer func() {
+		s.observe("refund", currency, refund.Value, err)
+	}()
+
+	r := storage.Refund{
+		TransactionID:  refund.TransactionID,
+		Initiator:      initiator(refund.Initiator),
+		IdempotencyKey: refund.IdempotencyKey,
+	}
+	if !refund.Value.IsZero() {
+		var v validation
+		checkAmount(&v, "value", refund.Value)
+		if err := v.err(); err != nil {
+			return 0, err
+		}
+
+		t, err := s.storage.GetTransaction(ctx, refund.TransactionID)
+		if err != nil {
+			return 0, fmt.Errorf("getting transaction from storage: %w", err)
+		}
+
+		currency = payeeCurrency(t)
+		s.checkLimit(&v, "value", refund.Value, currency)
+		if err := v.err(); err != nil {
+			return 0, err
+		}
+		if r.Value, err = toMinorUnits(refund.Value, currency); err != nil {
+			return 0, err
+		}
+	}
+
+	transactionID, err = s.storage.RefundTransaction(ctx, r)
+	if err != nil {
+		return 0, fmt.Errorf("refunding transaction in storage: %w", err)
+	}
+
+	return transactionID, nil
+}
+
+// LockQuote fixes the current rate, so a client can show it before committing a transfer.
+func (s *Service) LockQuote(ctx context.Context, from, to money.Currency) (_ Quote, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.LockQuote", "from", from, "to", to)
+	defer span.End(&err)
+
+	if s.rates == nil {
+		return Quote{}, fmt.Errorf("%w: %s to %s", ErrConversionUnsupported, from, to)
+	}
+
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		return Quote{}, fmt.Errorf("%w: %s", ErrConversionUnsupported, err)
+	}
+
+	quote := Quote{
+		ID:        uuid.New().String(),
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		ExpiresAt: s.now().Add(s.quoteTTL),
+	}
+	q := storage.Quote{
+		ID:           quote.ID,
+		FromCurrency: string(quote.From),
+		ToCurrency:   string(quote.To),
+		Rate:         quote.Rate.String(),
+		ExpiresAt:    quote.ExpiresAt,
+	}
+	if err := s.storage.AddQuote(ctx, q); err != nil {
+		return Quote{}, fmt.Errorf("adding quote into storage: %w", err)
+	}
+
+	return quote, nil
+}
+
+func (s *Service) GetOperations(ctx context.Context, filter Filter) (_ OperationsPage, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.GetOperations", "wallet_id", filter.WalletID)
+	defer span.End(&err)
+
+	w, err := s.storage.GetWallet(ctx, filter.WalletID)
+	if err != nil {
+		return OperationsPage{}, fmt.Errorf("getting wallet from storage: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultOperationsLimit
+	}
+	if limit > maxOperationsLimit {
+		limit = maxOperationsLimit
+	}
+
 	f := storage.Filter{
-		WalletID:  filter.WalletID,
-		Date:      filter.Date,
-		Direction: storage.Direction(filter.Direction),
+		WalletID: filter.WalletID,
+		From:     filter.From,
+		To:       filter.To,
+		// one more operation tells whether there is the next page
+		Limit: limit + 1,
+	}
+	if filter.Direction != nil {
+		direction := storage.Direction(*filter.Direction)
+		f.Direction = &direction
 	}
+	if filter.Cursor != "" {
+		after, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return OperationsPage{}, err
+		}
+		f.After = &after
+	}
+
 	storageOperations, err := s.storage.GetOperations(ctx, f)
 	if err != nil {
-		return nil, fmt.Errorf("getting operations from storage: %w", err)
+		return OperationsPage{}, fmt.Errorf("getting operations from storage: %w", err)
+	}
+
+	var page OperationsPage
+	if len(storageOperations) > limit {
+		storageOperations = storageOperations[:limit]
+		last := storageOperations[limit-1]
+		page.NextCursor = encodeCursor(storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
 	}
 
-	operations := make([]Operation, 0, len(storageOperations))
+	page.Operations = make([]Operation, 0, len(storageOperations))
 	for _, storageOperation := range storageOperations {
 		operation := Operation{
-			WalletID:  storageOperation.WalletID,
-			Value:     centsToDollars(storageOperation.Value),
-			Direction: int8(storageOperation.Direction),
-			Date:      storageOperation.Date,
+			ID:                    storageOperation.ID,
+			TransactionID:         storageOperation.TransactionID,
+			OriginalTransactionID: storageOperation.OriginalTransactionID.Int64,
+			WalletID:              storageOperation.WalletID,
+			CounterpartyWalletID:  storageOperation.CounterpartyWalletID.Int64,
+			Value:                 fromMinorUnits(storageOperation.Value, money.Currency(w.Currency)),
+			Direction:             int8(storageOperation.Direction),
+			Date:                  storageOperation.Date,
+			CreatedAt:             storageOperation.CreatedAt,
+		}
+		page.Operations = append(page.Operations, operation)
+	}
+
+	return page, nil
+}
+
+// Authorize reserves money of the source wallet, so it is not available for spending until the hold
+// is captured, voided or expired.
+func (s *Service) Authorize(ctx context.Context, authorization Authorization) (_ Hold, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.Authorize", "from_wallet_id", authorization.FromWalletID, "to_wallet_id", authorization.ToWalletID)
+	defer span.End(&err)
+
+	var currency money.Currency
+	defer func() {
+		s.observe("authorization", currency, authorization.Value, err)
+	}()
+
+	var v validation
+	checkAmount(&v, "value", authorization.Value)
+	if authorization.FromWalletID == authorization.ToWalletID {
+		v.add("to_wallet_id", fmt.Errorf("%w: %d", ErrSameWallet, authorization.ToWalletID))
+	}
+
+	from, fromFound, err := s.findWallet(ctx, &v, "from_wallet_id", authorization.FromWalletID)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	to, toFound, err := s.findWallet(ctx, &v, "to_wallet_id", authorization.ToWalletID)
+	if err != nil {
+		return Hold{}, err
+	}
+
+	var value int64
+	if fromFound {
+		currency = money.Currency(from.Currency)
+		checkCurrency(&v, authorization.Currency, currency, "authorization")
+		s.checkLimit(&v, "value", authorization.Value, currency)
+		value = minorUnits(&v, "value", authorization.Value, currency)
+	}
+	if fromFound && toFound && from.Currency != to.Currency {
+		v.add("to_wallet_id", fmt.Errorf("%w: authorization from %s wallet to %s wallet", ErrCurrencyMismatch, from.Currency, to.Currency))
+	}
+	if err := v.err(); err != nil {
+		return Hold{}, err
+	}
+
+	a := storage.Authorization{
+		FromWalletID:   authorization.FromWalletID,
+		ToWalletID:     authorization.ToWalletID,
+		Value:          value,
+		Currency:       from.Currency,
+		ExpiresAt:      s.now().Add(s.holdTTL),
+		Initiator:      initiator(authorization.Initiator),
+		IdempotencyKey: authorization.IdempotencyKey,
+	}
+	hold, err := s.storage.AuthorizeHold(ctx, a)
+	if err != nil {
+		return Hold{}, fmt.Errorf("authorizing hold in storage: %w", err)
+	}
+
+	return toHold(hold), nil
+}
+
+// Capture pays the held money to the destination wallet. A hold is captured once, a partial capture
+// releases the rest of the hold.
+func (s *Service) Capture(ctx context.Context, capture Capture) (_ Hold, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.Capture", "hold_id", capture.HoldID)
+	defer span.End(&err)
+
+	var currency money.Currency
+	defer func() {
+		s.observe("capture", currency, capture.Value, err)
+	}()
+
+	c := storage.Capture{HoldID: capture.HoldID, Now: s.now()}
+	if !capture.Value.IsZero() {
+		var v validation
+		checkAmount(&v, "value", capture.Value)
+		if err := v.err(); err != nil {
+			return Hold{}, err
+		}
+
+		hold, err := s.storage.GetHold(ctx, capture.HoldID)
+		if err != nil {
+			return Hold{}, fmt.Errorf("getting hold from storage: %w", err)
+		}
+
+		currency = money.Currency(hold.Currency)
+		s.checkLimit(&v, "value", capture.Value, currency)
+		if err := v.err(); err != nil {
+			return Hold{}, err
+		}
+		if c.Value, err = toMinorUnits(capture.Value, currency); err != nil {
+			return Hold{}, err
+		}
+	}
+
+	hold, err := s.storage.CaptureHold(ctx, c)
+	if err != nil {
+		return Hold{}, fmt.Errorf("capturing hold in storage: %w", err)
+	}
+
+	return toHold(hold), nil
+}
+
+// Void releases the held money without paying it.
+func (s *Service) Void(ctx context.Context, holdID int64) (_ Hold, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.Void", "hold_id", holdID)
+	defer span.End(&err)
+
+	hold, err := s.storage.VoidHold(ctx, holdID, s.now())
+	if err != nil {
+		return Hold{}, fmt.Errorf("voiding hold in storage: %w", err)
+	}
+
+	return toHold(hold), nil
+}
+
+// SweepHolds releases expired holds every interval until the context is done.
+func (s *Service) SweepHolds(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expired, err := s.expireHolds(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to expire holds", "error", err)
+			continue
+		}
+
+		if expired > 0 {
+			logging.FromContext(ctx).Info("expired holds", "count", expired)
 		}
-		operations = append(operations, operation)
 	}
+}
 
-	return operations, nil
+func (s *Service) expireHolds(ctx context.Context) (expired int64, err error) {
+	ctx, span := spanlog.Start(ctx, "wallet.ExpireHolds")
+	defer span.End(&err)
+
+	return s.storage.ExpireHolds(ctx, s.now())
+}
+
+func (s *Service) observe(operation string, currency money.Currency, amount money.Amount, err error) {
+	if s.observer != nil {
+		s.observer.ObserveOperation(operation, currency, amount, err)
+	}
 }
 
-func dollarsToCents(dollars float64) int64 {
-	return int64(dollars * 100)
+func (s *Service) exchangeRate(ctx context.Context, quoteID string, from, to money.Currency) (money.Amount, error) {
+	if quoteID == "" {
+		if s.rates == nil {
+			return money.Amount{}, fmt.Errorf("%w: %s to %s", ErrConversionUnsupported, from, to)
+		}
+
+		rate, err := s.rates.Rate(ctx, from, to)
+		if err != nil {
+			return money.Amount{}, fmt.Errorf("%w: %s", ErrConversionUnsupported, err)
+		}
+
+		return rate, nil
+	}
+
+	quote, err := s.storage.GetQuote(ctx, quoteID)
+	if err != nil {
+		return money.Amount{}, fmt.Errorf("getting quote from storage: %w", err)
+	}
+
+	if !s.now().Before(quote.ExpiresAt) {
+		return money.Amount{}, fmt.Errorf("%w: %s", ErrQuoteExpired, quoteID)
+	}
+
+	// the storage refuses a used quote as well, this check only saves the exchange of a transfer which would fail
+	if quote.TransactionID.Valid {
+		return money.Amount{}, fmt.Errorf("%w: %s", ErrQuoteUsed, quoteID)
+	}
+
+	if quote.FromCurrency != string(from) || quote.ToCurrency != string(to) {
+		return money.Amount{}, fmt.Errorf("%w: quote %s to %s for transfer %s to %s",
+			ErrCurrencyMismatch, quote.FromCurrency, quote.ToCurrency, from, to)
+	}
+
+	rate, err := money.ParseAmount(quote.Rate)
+	if err != nil {
+		return money.Amount{}, fmt.Errorf("parsing quote rate: %w", err)
+	}
+
+	return rate, nil
+}
+
+type cursorDTO struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+// encodeCursor makes an opaque token of the last operation on a page.
+func encodeCursor(cursor storage.Cursor) string {
+	dto := cursorDTO{CreatedAt: cursor.CreatedAt, ID: cursor.ID}
+	marshaled, _ := json.Marshal(dto)
+	return base64.RawURLEncoding.EncodeToString(marshaled)
+}
+
+func decodeCursor(token string) (storage.Cursor, error) {
+	marshaled, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return storage.Cursor{}, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	var dto cursorDTO
+	if err := json.Unmarshal(marshaled, &dto); err != nil {
+		return storage.Cursor{}, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	if dto.CreatedAt.IsZero() || dto.ID <= 0 {
+		return storage.Cursor{}, fmt.Errorf("%w: %s", ErrInvalidCursor, token)
+	}
+
+	return storage.Cursor{CreatedAt: dto.CreatedAt, ID: dto.ID}, nil
+}
+
+func toHold(hold storage.Hold) Hold {
+	currency := money.Currency(hold.Currency)
+	return Hold{
+		ID:            hold.ID,
+		TransactionID: hold.TransactionID,
+		FromWalletID:  hold.FromWalletID,
+		ToWalletID:    hold.ToWalletID,
+		Value:         fromMinorUnits(hold.Value, currency),
+		CapturedValue: fromMinorUnits(hold.CapturedValue, currency),
+		Currency:      currency,
+		Status:        string(hold.Status),
+		ExpiresAt:     hold.ExpiresAt,
+	}
+}
+
+// payeeCurrency is the currency of the deposit leg (direction 0), refunds are counted in it.
+func payeeCurrency(transaction storage.Transaction) money.Currency {
+	for _, leg := range transaction.Legs {
+		if leg.Direction == 0 {
+			return money.Currency(leg.Currency)
+		}
+	}
+
+	return ""
+}
+
+func initiator(name string) string {
+	if name == "" {
+		return defaultInitiator
+	}
+
+	return name
+}
+
+func toMinorUnits(amount money.Amount, currency money.Currency) (int64, error) {
+	units, err := amount.MinorUnits(currency.Exponent())
+	if err != nil {
+		return 0, fmt.Errorf("%w in %s", err, currency)
+	}
+
+	return units, nil
 }
 
-func centsToDollars(cents int64) float64 {
-	return float64(cents) / 100.0
+func fromMinorUnits(units int64, currency money.Currency) money.Amount {
+	return money.NewAmount(units, currency.Exponent())
 }
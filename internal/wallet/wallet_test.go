@@ -2,12 +2,16 @@ package wallet
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 
+	"payment-system/internal/money"
 	"payment-system/internal/storage"
 )
 
@@ -30,45 +34,166 @@ func TestService_AddWallet_ReturnsWalletID(t *testing.T) {
 	require.Equal(t, int64(1), walletID)
 }
 
+func TestService_GetBalance_ReturnsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{}, storage.ErrWalletNotFound)
+	service := New(mockWalletStorage)
+	_, err := service.GetBalance(context.Background(), 1)
+	require.True(t, errors.Is(err, ErrWalletNotFound))
+}
+
+func TestService_GetBalance_ReturnsBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	updatedAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{
+		ID:        1,
+		Currency:  "KWD",
+		Value:     1005,
+		Held:      5,
+		UpdatedAt: updatedAt,
+	}, nil)
+	service := New(mockWalletStorage)
+	balance, err := service.GetBalance(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, Balance{
+		WalletID:  1,
+		Value:     money.NewAmount(1005, 3),
+		Available: money.NewAmount(1000, 3),
+		Currency:  "KWD",
+		UpdatedAt: updatedAt,
+	}, balance)
+}
+
 func TestService_DepositMoney_ReturnsError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockWalletStorage := NewMockwalletStorage(ctrl)
-	mockWalletStorage.EXPECT().DepositMoney(gomock.Any(), gomock.Any()).Return(fmt.Errorf("something went wrong"))
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().DepositMoney(gomock.Any(), gomock.Any()).Return(int64(0), fmt.Errorf("something went wrong"))
 	service := New(mockWalletStorage)
-	err := service.DepositMoney(context.Background(), Deposit{})
+	_, err := service.DepositMoney(context.Background(), Deposit{Value: money.NewAmount(1, 0)})
 	require.Error(t, err)
 }
 
 func TestService_DepositMoney_ReturnsNoError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockWalletStorage := NewMockwalletStorage(ctrl)
-	mockWalletStorage.EXPECT().DepositMoney(gomock.Any(), gomock.Any()).Return(nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().DepositMoney(gomock.Any(), gomock.Any()).Return(int64(7), nil)
+	service := New(mockWalletStorage)
+	transactionID, err := service.DepositMoney(context.Background(), Deposit{Value: money.NewAmount(1, 0)})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), transactionID)
+}
+
+func TestService_WithdrawMoney_ReturnsInsufficientFunds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().WithdrawMoney(gomock.Any(), gomock.Any()).Return(int64(0), storage.ErrInsufficientFunds)
+	service := New(mockWalletStorage)
+	_, err := service.WithdrawMoney(context.Background(), Withdrawal{Value: money.NewAmount(1, 0)})
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+}
+
+func TestService_WithdrawMoney_ReportsOperationToObserver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().WithdrawMoney(gomock.Any(), gomock.Any()).Return(int64(0), storage.ErrInsufficientFunds)
+	mockObserver := NewMockOperationObserver(ctrl)
+	mockObserver.EXPECT().ObserveOperation("withdrawal", money.Currency("USD"), money.NewAmount(1, 0), gomock.Any()).
+		Do(func(_ string, _ money.Currency, _ money.Amount, err error) {
+			require.True(t, errors.Is(err, ErrInsufficientFunds))
+		})
+	service := New(mockWalletStorage, WithObserver(mockObserver))
+	_, err := service.WithdrawMoney(context.Background(), Withdrawal{Value: money.NewAmount(1, 0)})
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+}
+
+func TestService_WithdrawMoney_ReturnsNoError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "JPY"}, nil)
+	mockWalletStorage.EXPECT().WithdrawMoney(gomock.Any(), storage.Withdrawal{
+		WalletID:       1,
+		Value:          500,
+		Currency:       "JPY",
+		Destination:    "iban:DE89370400440532013000",
+		Initiator:      "api",
+		IdempotencyKey: "foo",
+	}).Return(int64(1), nil)
 	service := New(mockWalletStorage)
-	err := service.DepositMoney(context.Background(), Deposit{})
+	withdrawal := Withdrawal{
+		WalletID:       1,
+		Value:          money.NewAmount(500, 0),
+		Destination:    "iban:DE89370400440532013000",
+		IdempotencyKey: "foo",
+	}
+	_, err := service.WithdrawMoney(context.Background(), withdrawal)
 	require.NoError(t, err)
 }
 
 func TestService_TransferMoney_ReturnsError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockWalletStorage := NewMockwalletStorage(ctrl)
-	mockWalletStorage.EXPECT().TransferMoney(gomock.Any(), gomock.Any()).Return(fmt.Errorf("something went wrong"))
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil).Times(2)
+	mockWalletStorage.EXPECT().TransferMoney(gomock.Any(), gomock.Any()).Return(int64(0), fmt.Errorf("something went wrong"))
 	service := New(mockWalletStorage)
-	err := service.TransferMoney(context.Background(), Transfer{})
+	_, err := service.TransferMoney(context.Background(), Transfer{FromWalletID: 1, ToWalletID: 2, Value: money.NewAmount(1, 0)})
 	require.Error(t, err)
 }
 
 func TestService_TransferMoney_ReturnsNoError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockWalletStorage := NewMockwalletStorage(ctrl)
-	mockWalletStorage.EXPECT().TransferMoney(gomock.Any(), gomock.Any()).Return(nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil).Times(2)
+	mockWalletStorage.EXPECT().TransferMoney(gomock.Any(), gomock.Any()).Return(int64(1), nil)
 	service := New(mockWalletStorage)
-	err := service.TransferMoney(context.Background(), Transfer{})
+	_, err := service.TransferMoney(context.Background(), Transfer{FromWalletID: 1, ToWalletID: 2, Value: money.NewAmount(1, 0)})
+	require.NoError(t, err)
+}
+
+func TestService_TransferMoney_ReportsAllViolations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{}, storage.ErrWalletNotFound).Times(2)
+	service := New(mockWalletStorage)
+	_, err := service.TransferMoney(context.Background(), Transfer{FromWalletID: 1, ToWalletID: 1, Value: money.NewAmount(-5, 0)})
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.True(t, errors.Is(err, ErrValidation))
+	require.True(t, errors.Is(err, ErrWalletNotFound))
+	fields := make([]string, 0, len(validationErr.Violations))
+	for _, v := range validationErr.Violations {
+		fields = append(fields, v.Field)
+	}
+	require.Equal(t, []string{"value", "to_wallet_id", "from_wallet_id", "to_wallet_id"}, fields)
+}
+
+func TestService_DepositMoney_RejectsAmountOverLimitOfWalletCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "JPY"}, nil)
+	mockWalletStorage.EXPECT().DepositMoney(gomock.Any(), gomock.Any()).Return(int64(7), nil)
+	service := New(mockWalletStorage, WithMaxAmounts(map[money.Currency]money.Amount{
+		"USD": money.NewAmount(1000, 0),
+		"JPY": money.NewAmount(150000, 0),
+	}))
+
+	_, err := service.DepositMoney(context.Background(), Deposit{WalletID: 1, Value: money.NewAmount(100001, 2)})
+	require.True(t, errors.Is(err, ErrAmountLimitExceeded), "got error %v", err)
+
+	_, err = service.DepositMoney(context.Background(), Deposit{WalletID: 2, Value: money.NewAmount(100001, 0)})
 	require.NoError(t, err)
 }
 
 func TestService_GetOperationsReturnsError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
 	mockWalletStorage.EXPECT().GetOperations(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("something went wrong"))
 	service := New(mockWalletStorage)
 	_, err := service.GetOperations(context.Background(), Filter{})
@@ -78,116 +203,572 @@ func TestService_GetOperationsReturnsError(t *testing.T) {
 func TestService_GetOperationsReturnsOperations(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	firstCreatedAt := time.Date(2021, 5, 22, 10, 0, 0, 0, time.UTC)
+	secondCreatedAt := time.Date(2021, 6, 30, 12, 30, 0, 0, time.UTC)
 	mockWalletStorage.EXPECT().GetOperations(gomock.Any(), gomock.Any()).Return([]storage.Operation{{
-		WalletID:  2,
-		Value:     115,
-		Direction: 1,
-		Date:      "2021-06-30",
-	}, {
+		ID:        1,
 		WalletID:  2,
 		Value:     1102,
 		Direction: 0,
 		Date:      "2021-05-22",
+		CreatedAt: firstCreatedAt,
+	}, {
+		ID:        2,
+		WalletID:  2,
+		Value:     115,
+		Direction: 1,
+		Date:      "2021-06-30",
+		CreatedAt: secondCreatedAt,
 	}}, nil)
 	service := New(mockWalletStorage)
-	operations, err := service.GetOperations(context.Background(), Filter{})
+	page, err := service.GetOperations(context.Background(), Filter{})
 	require.NoError(t, err)
+	require.Empty(t, page.NextCursor)
 	require.Equal(t, []Operation{{
+		ID:        1,
 		WalletID:  2,
-		Value:     1.15,
-		Direction: 1,
-		Date:      "2021-06-30",
+		Value:     money.NewAmount(1102, 2),
+		Direction: 0,
+		Date:      "2021-05-22",
+		CreatedAt: firstCreatedAt,
 	},
 		{
+			ID:        2,
 			WalletID:  2,
-			Value:     11.02,
-			Direction: 0,
-			Date:      "2021-05-22",
-		}}, operations)
+			Value:     money.NewAmount(115, 2),
+			Direction: 1,
+			Date:      "2021-06-30",
+			CreatedAt: secondCreatedAt,
+		}}, page.Operations)
+}
+
+func TestService_GetOperations_ReturnsPages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	direction := int8(1)
+	storageDirection := storage.Direction(1)
+	firstCreatedAt := time.Date(2021, 5, 22, 10, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil).Times(2)
+	mockWalletStorage.EXPECT().GetOperations(gomock.Any(), storage.Filter{
+		WalletID:  2,
+		From:      "2021-05-01",
+		To:        "2021-06-30",
+		Direction: &storageDirection,
+		Limit:     2,
+	}).Return([]storage.Operation{
+		{ID: 1, WalletID: 2, Value: 1, Direction: 1, Date: "2021-05-22", CreatedAt: firstCreatedAt},
+		{ID: 2, WalletID: 2, Value: 2, Direction: 1, Date: "2021-06-30", CreatedAt: firstCreatedAt.AddDate(0, 1, 8)},
+	}, nil)
+	service := New(mockWalletStorage)
+	filter := Filter{
+		WalletID:  2,
+		From:      "2021-05-01",
+		To:        "2021-06-30",
+		Direction: &direction,
+		Limit:     1,
+	}
+	page, err := service.GetOperations(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, page.Operations, 1)
+	require.NotEmpty(t, page.NextCursor)
+
+	mockWalletStorage.EXPECT().GetOperations(gomock.Any(), storage.Filter{
+		WalletID:  2,
+		From:      "2021-05-01",
+		To:        "2021-06-30",
+		Direction: &storageDirection,
+		After:     &storage.Cursor{CreatedAt: firstCreatedAt, ID: 1},
+		Limit:     2,
+	}).Return([]storage.Operation{
+		{ID: 2, WalletID: 2, Value: 2, Direction: 1, Date: "2021-06-30", CreatedAt: firstCreatedAt.AddDate(0, 1, 8)},
+	}, nil)
+	filter.Cursor = page.NextCursor
+	page, err = service.GetOperations(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, page.Operations, 1)
+	require.Empty(t, page.NextCursor)
+}
+
+func TestService_GetOperations_ReturnsErrorOnInvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	service := New(mockWalletStorage)
+	_, err := service.GetOperations(context.Background(), Filter{Cursor: "boo"})
+	require.True(t, errors.Is(err, ErrInvalidCursor))
+}
+
+func TestService_GetTransaction_ReturnsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetTransaction(gomock.Any(), int64(1)).Return(storage.Transaction{}, storage.ErrTransactionNotFound)
+	service := New(mockWalletStorage)
+	_, err := service.GetTransaction(context.Background(), 1)
+	require.True(t, errors.Is(err, ErrTransactionNotFound))
+}
+
+func TestService_GetTransaction_ReturnsLegs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	createdAt := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetTransaction(gomock.Any(), int64(1)).Return(storage.Transaction{
+		ID:        1,
+		Type:      storage.TransactionTransfer,
+		Status:    storage.TransactionCompleted,
+		Initiator: "api",
+		CreatedAt: createdAt,
+		Legs: []storage.Leg{
+			{OperationID: 1, WalletID: 2, CounterpartyWalletID: sql.NullInt64{Int64: 3, Valid: true}, Value: 150, Direction: 1, Currency: "USD", CreatedAt: createdAt},
+			{OperationID: 2, WalletID: 3, CounterpartyWalletID: sql.NullInt64{Int64: 2, Valid: true}, Value: 217, Direction: 0, Currency: "JPY", CreatedAt: createdAt},
+		},
+	}, nil)
+	service := New(mockWalletStorage)
+	transaction, err := service.GetTransaction(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, Transaction{
+		ID:        1,
+		Type:      "transfer",
+		Status:    "completed",
+		Initiator: "api",
+		CreatedAt: createdAt,
+		Legs: []Leg{
+			{OperationID: 1, WalletID: 2, CounterpartyWalletID: 3, Value: money.NewAmount(150, 2), Currency: "USD", Direction: 1, CreatedAt: createdAt},
+			{OperationID: 2, WalletID: 3, CounterpartyWalletID: 2, Value: money.NewAmount(217, 0), Currency: "JPY", Direction: 0, CreatedAt: createdAt},
+		},
+	}, transaction)
+}
+
+func TestService_DepositMoney_ReturnsErrorOnSubCentValue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	service := New(mockWalletStorage)
+	_, err := service.DepositMoney(context.Background(), Deposit{Value: money.NewAmount(1553, 3)})
+	require.True(t, errors.Is(err, ErrInvalidAmount))
+	require.True(t, errors.Is(err, money.ErrPrecision), "got error %v", err)
+}
+
+func TestService_DepositMoney_ReturnsErrorOnCurrencyMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), gomock.Any()).Return(storage.Wallet{Currency: "USD"}, nil)
+	service := New(mockWalletStorage)
+	_, err := service.DepositMoney(context.Background(), Deposit{Value: money.NewAmount(1, 0), Currency: "EUR"})
+	require.True(t, errors.Is(err, ErrCurrencyMismatch))
+}
+
+func TestService_DepositMoney_UsesCurrencyExponent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "KWD"}, nil)
+	mockWalletStorage.EXPECT().DepositMoney(gomock.Any(), storage.Deposit{WalletID: 1, Value: 1005, Currency: "KWD", Initiator: "api"}).Return(int64(1), nil)
+	service := New(mockWalletStorage)
+	_, err := service.DepositMoney(context.Background(), Deposit{WalletID: 1, Value: money.NewAmount(1005, 3)})
+	require.NoError(t, err)
+}
+
+func TestService_TransferMoney_ReturnsErrorOnCrossCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil).Times(2)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "JPY"}, nil).Times(2)
+	service := New(mockWalletStorage)
+	transfer := Transfer{FromWalletID: 1, ToWalletID: 2, Value: money.NewAmount(1, 0)}
+	_, err := service.TransferMoney(context.Background(), transfer)
+	require.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	transfer.ConvertCurrency = true
+	_, err = service.TransferMoney(context.Background(), transfer)
+	require.True(t, errors.Is(err, ErrConversionUnsupported))
+}
+
+func TestService_TransferMoney_ConvertsCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockFXRateProvider := NewMockFXRateProvider(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "JPY"}, nil)
+	mockFXRateProvider.EXPECT().Rate(gomock.Any(), money.Currency("USD"), money.Currency("JPY")).Return(money.NewAmount(14512, 2), nil)
+	mockWalletStorage.EXPECT().TransferMoney(gomock.Any(), storage.Transfer{
+		FromWalletID:     1,
+		ToWalletID:       2,
+		Value:            150,
+		Currency:         "USD",
+		DestinationValue: 217,
+		Exchange: &storage.Exchange{
+			Rate:                "145.12",
+			SourceCurrency:      "USD",
+			DestinationCurrency: "JPY",
+		},
+		Initiator:      "api",
+		IdempotencyKey: "foo",
+	}).Return(int64(1), nil)
+	service := New(mockWalletStorage, WithFXRates(mockFXRateProvider, time.Minute))
+	transfer := Transfer{
+		FromWalletID:    1,
+		ToWalletID:      2,
+		Value:           money.NewAmount(150, 2),
+		ConvertCurrency: true,
+		IdempotencyKey:  "foo",
+	}
+	_, err := service.TransferMoney(context.Background(), transfer)
+	require.NoError(t, err)
+}
+
+func TestService_TransferMoney_UsesLockedQuote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockFXRateProvider := NewMockFXRateProvider(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil).Times(2)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "EUR"}, nil).Times(2)
+	mockWalletStorage.EXPECT().GetQuote(gomock.Any(), "quote").Return(storage.Quote{
+		ID:           "quote",
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Rate:         "0.5",
+		ExpiresAt:    now.Add(time.Second),
+	}, nil).Times(2)
+	mockWalletStorage.EXPECT().TransferMoney(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, transfer storage.Transfer) (int64, error) {
+			require.Equal(t, int64(50), transfer.DestinationValue)
+			require.Equal(t, "quote", transfer.Exchange.QuoteID)
+			return 1, nil
+		})
+	service := New(mockWalletStorage, WithFXRates(mockFXRateProvider, time.Minute))
+	service.now = func() time.Time { return now }
+	transfer := Transfer{
+		FromWalletID:    1,
+		ToWalletID:      2,
+		Value:           money.NewAmount(1, 0),
+		ConvertCurrency: true,
+		QuoteID:         "quote",
+	}
+	_, err := service.TransferMoney(context.Background(), transfer)
+	require.NoError(t, err)
+
+	service.now = func() time.Time { return now.Add(time.Second) }
+	_, err = service.TransferMoney(context.Background(), transfer)
+	require.True(t, errors.Is(err, ErrQuoteExpired))
+}
+
+func TestService_TransferMoney_RejectsUsedQuote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "EUR"}, nil)
+	mockWalletStorage.EXPECT().GetQuote(gomock.Any(), "quote").Return(storage.Quote{
+		ID:            "quote",
+		FromCurrency:  "USD",
+		ToCurrency:    "EUR",
+		Rate:          "0.5",
+		ExpiresAt:     now.Add(time.Second),
+		TransactionID: sql.NullInt64{Int64: 7, Valid: true},
+	}, nil)
+	service := New(mockWalletStorage, WithFXRates(NewMockFXRateProvider(ctrl), time.Minute))
+	service.now = func() time.Time { return now }
+	_, err := service.TransferMoney(context.Background(), Transfer{
+		FromWalletID:    1,
+		ToWalletID:      2,
+		Value:           money.NewAmount(1, 0),
+		ConvertCurrency: true,
+		QuoteID:         "quote",
+	})
+	require.True(t, errors.Is(err, ErrQuoteUsed), "got error %v", err)
+}
+
+func TestService_Authorize_ReturnsHold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().AuthorizeHold(gomock.Any(), storage.Authorization{
+		FromWalletID:   1,
+		ToWalletID:     2,
+		Value:          1050,
+		Currency:       "USD",
+		ExpiresAt:      now.Add(time.Hour),
+		Initiator:      "api",
+		IdempotencyKey: "foo",
+	}).Return(storage.Hold{
+		ID:            3,
+		TransactionID: 4,
+		FromWalletID:  1,
+		ToWalletID:    2,
+		Value:         1050,
+		Currency:      "USD",
+		Status:        storage.HoldAuthorized,
+		ExpiresAt:     now.Add(time.Hour),
+	}, nil)
+	service := New(mockWalletStorage, WithHoldTTL(time.Hour))
+	service.now = func() time.Time { return now }
+	authorization := Authorization{
+		FromWalletID:   1,
+		ToWalletID:     2,
+		Value:          money.NewAmount(1050, 2),
+		IdempotencyKey: "foo",
+	}
+	hold, err := service.Authorize(context.Background(), authorization)
+	require.NoError(t, err)
+	require.Equal(t, Hold{
+		ID:            3,
+		TransactionID: 4,
+		FromWalletID:  1,
+		ToWalletID:    2,
+		Value:         money.NewAmount(1050, 2),
+		CapturedValue: money.NewAmount(0, 2),
+		Currency:      "USD",
+		Status:        "authorized",
+		ExpiresAt:     now.Add(time.Hour),
+	}, hold)
+}
+
+func TestService_Authorize_ReturnsErrorOnCrossCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(1)).Return(storage.Wallet{ID: 1, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().GetWallet(gomock.Any(), int64(2)).Return(storage.Wallet{ID: 2, Currency: "EUR"}, nil)
+	service := New(mockWalletStorage)
+	_, err := service.Authorize(context.Background(), Authorization{FromWalletID: 1, ToWalletID: 2, Value: money.NewAmount(1, 0)})
+	require.True(t, errors.Is(err, ErrCurrencyMismatch))
 }
 
-func Test_dollarsToCents(t *testing.T) {
+func TestService_Capture_ConvertsPartialValue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().GetHold(gomock.Any(), int64(3)).Return(storage.Hold{ID: 3, Value: 1050, Currency: "USD"}, nil)
+	mockWalletStorage.EXPECT().CaptureHold(gomock.Any(), storage.Capture{HoldID: 3, Value: 500, Now: now}).
+		Return(storage.Hold{}, storage.ErrHoldNotAuthorized)
+	service := New(mockWalletStorage)
+	service.now = func() time.Time { return now }
+	_, err := service.Capture(context.Background(), Capture{HoldID: 3, Value: money.NewAmount(5, 0)})
+	require.True(t, errors.Is(err, ErrHoldNotAuthorized))
+}
+
+func TestService_Capture_CapturesWholeHold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockWalletStorage.EXPECT().CaptureHold(gomock.Any(), storage.Capture{HoldID: 3, Now: now}).
+		Return(storage.Hold{ID: 3, Value: 1050, CapturedValue: 1050, Currency: "USD", Status: storage.HoldCaptured}, nil)
+	service := New(mockWalletStorage)
+	service.now = func() time.Time { return now }
+	hold, err := service.Capture(context.Background(), Capture{HoldID: 3})
+	require.NoError(t, err)
+	require.Equal(t, "captured", hold.Status)
+	require.Equal(t, money.NewAmount(1050, 2), hold.CapturedValue)
+}
+
+func TestService_Void_ReturnsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().VoidHold(gomock.Any(), int64(3), gomock.Any()).Return(storage.Hold{}, storage.ErrHoldNotFound)
+	service := New(mockWalletStorage)
+	_, err := service.Void(context.Background(), 3)
+	require.True(t, errors.Is(err, ErrHoldNotFound))
+}
+
+func TestService_RefundTransaction_ConvertsPartialValue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetTransaction(gomock.Any(), int64(1)).Return(storage.Transaction{
+		ID: 1,
+		Legs: []storage.Leg{
+			{WalletID: 2, Value: 150, Direction: 1, Currency: "USD"},
+			{WalletID: 3, Value: 217, Direction: 0, Currency: "JPY"},
+		},
+	}, nil)
+	mockWalletStorage.EXPECT().RefundTransaction(gomock.Any(), storage.Refund{
+		TransactionID:  1,
+		Value:          100,
+		Initiator:      "api",
+		IdempotencyKey: "key",
+	}).Return(int64(4), nil)
+	service := New(mockWalletStorage)
+	transactionID, err := service.RefundTransaction(context.Background(),
+		Refund{TransactionID: 1, Value: money.NewAmount(100, 0), IdempotencyKey: "key"})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), transactionID)
+}
+
+func TestService_RefundTransaction_ReturnsErrorOnSubUnitValue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().GetTransaction(gomock.Any(), int64(1)).Return(storage.Transaction{
+		ID:   1,
+		Legs: []storage.Leg{{WalletID: 3, Value: 217, Direction: 0, Currency: "JPY"}},
+	}, nil)
+	service := New(mockWalletStorage)
+	_, err := service.RefundTransaction(context.Background(), Refund{TransactionID: 1, Value: money.NewAmount(5, 1)})
+	require.True(t, errors.Is(err, ErrInvalidAmount))
+}
+
+func TestService_RefundTransaction_RefundsRest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockWalletStorage.EXPECT().RefundTransaction(gomock.Any(), storage.Refund{TransactionID: 1, Initiator: "api"}).
+		Return(int64(0), storage.ErrRefundExceedsTransaction)
+	service := New(mockWalletStorage)
+	_, err := service.RefundTransaction(context.Background(), Refund{TransactionID: 1})
+	require.True(t, errors.Is(err, ErrRefundExceedsTransaction))
+}
+
+func TestService_LockQuote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockWalletStorage := NewMockwalletStorage(ctrl)
+	mockFXRateProvider := NewMockFXRateProvider(ctrl)
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	mockFXRateProvider.EXPECT().Rate(gomock.Any(), money.Currency("USD"), money.Currency("EUR")).Return(money.NewAmount(92, 2), nil)
+	mockWalletStorage.EXPECT().AddQuote(gomock.Any(), gomock.Any()).Return(nil)
+	service := New(mockWalletStorage, WithFXRates(mockFXRateProvider, time.Minute))
+	service.now = func() time.Time { return now }
+	quote, err := service.LockQuote(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.NotEmpty(t, quote.ID)
+	require.Equal(t, money.NewAmount(92, 2), quote.Rate)
+	require.Equal(t, now.Add(time.Minute), quote.ExpiresAt)
+}
+
+func TestService_LockQuote_ReturnsErrorWithoutRates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	service := New(NewMockwalletStorage(ctrl))
+	_, err := service.LockQuote(context.Background(), "USD", "EUR")
+	require.True(t, errors.Is(err, ErrConversionUnsupported))
+}
+
+func Test_toMinorUnits(t *testing.T) {
 	type args struct {
-		dollars float64
+		amount   money.Amount
+		currency money.Currency
 	}
 	tests := []struct {
-		name string
-		args args
-		want int64
+		name    string
+		args    args
+		want    int64
+		wantErr bool
 	}{
 		{
 			name: "dollars without cents",
 			args: args{
-				dollars: 100,
+				amount:   money.NewAmount(100, 0),
+				currency: money.USD,
 			},
 			want: 10000,
 		},
 		{
 			name: "dollars with cents",
 			args: args{
-				dollars: 1.53,
+				amount:   money.NewAmount(153, 2),
+				currency: money.USD,
 			},
 			want: 153,
 		},
 		{
-			name: "dollars with cents to round",
+			name: "dollars with sub-cent precision",
 			args: args{
-				dollars: 1.553,
+				amount:   money.NewAmount(1553, 3),
+				currency: money.USD,
 			},
-			want: 155,
+			wantErr: true,
 		},
 		{
-			name: "only cents",
+			name: "cents which are not exact in float",
+			args: args{
+				amount:   money.NewAmount(29, 2),
+				currency: money.USD,
+			},
+			want: 29,
+		},
+		{
+			name: "yen without minor units",
+			args: args{
+				amount:   money.NewAmount(500, 0),
+				currency: "JPY",
+			},
+			want: 500,
+		},
+		{
+			name: "yen with fraction",
+			args: args{
+				amount:   money.NewAmount(55, 1),
+				currency: "JPY",
+			},
+			wantErr: true,
+		},
+		{
+			name: "dinar with fils",
 			args: args{
-				dollars: 0.55,
+				amount:   money.NewAmount(1553, 3),
+				currency: "KWD",
 			},
-			want: 55,
+			want: 1553,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := dollarsToCents(tt.args.dollars); got != tt.want {
-				t.Errorf("dollarsToCents() = %v, want %v", got, tt.want)
+			got, err := toMinorUnits(tt.args.amount, tt.args.currency)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("toMinorUnits() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("toMinorUnits() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_centsToDollars(t *testing.T) {
+func Test_fromMinorUnits(t *testing.T) {
 	type args struct {
-		cents int64
+		units    int64
+		currency money.Currency
 	}
 	tests := []struct {
 		name string
 		args args
-		want float64
+		want string
 	}{
 		{
 			name: "dollar without cents",
 			args: args{
-				cents: 100,
+				units:    100,
+				currency: money.USD,
 			},
-			want: 1,
+			want: "1.00",
 		},
 		{
 			name: "only cents",
 			args: args{
-				cents: 1,
+				units:    1,
+				currency: money.USD,
+			},
+			want: "0.01",
+		},
+		{
+			name: "yen",
+			args: args{
+				units:    115,
+				currency: "JPY",
 			},
-			want: 0.01,
+			want: "115",
 		},
 		{
-			name: "dollar with cents",
+			name: "dinar",
 			args: args{
-				cents: 115,
+				units:    115,
+				currency: "KWD",
 			},
-			want: 1.15,
+			want: "0.115",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := centsToDollars(tt.args.cents); got != tt.want {
-				t.Errorf("centsToDollars() = %v, want %v", got, tt.want)
+			if got := fromMinorUnits(tt.args.units, tt.args.currency).String(); got != tt.want {
+				t.Errorf("fromMinorUnits() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,9 +15,18 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"payment-system/internal/handlers/add_wallet"
+	"payment-system/internal/handlers/authorize_hold"
+	"payment-system/internal/handlers/capture_hold"
 	"payment-system/internal/handlers/deposit_money"
+	"payment-system/internal/handlers/get_balance"
 	"payment-system/internal/handlers/get_operations"
+	"payment-system/internal/handlers/get_transaction"
+	"payment-system/internal/handlers/httperror"
+	"payment-system/internal/handlers/refund_transaction"
 	"payment-system/internal/handlers/transfer_money"
+	"payment-system/internal/handlers/void_hold"
+	"payment-system/internal/handlers/withdraw_money"
+	"payment-system/internal/money"
 )
 
 func TestHappyPath(t *testing.T) {
@@ -35,9 +46,16 @@ func TestHappyPath(t *testing.T) {
 
 	fromWalletID := walletOutDTO.WalletID
 
-	// imitate duplicate operation
+	// imitate duplicate operation, the original response is replayed
+	replayedWalletOutDTO, err := addWallet(&httpClient, walletInDTO)
+	require.NoError(t, err)
+	require.Equal(t, fromWalletID, replayedWalletOutDTO.WalletID)
+
+	// reuse idempotency key for another wallet
+	walletInDTO.Currency = "EUR"
 	_, err = addWallet(&httpClient, walletInDTO)
-	require.Error(t, err)
+	requireErrorCode(t, err, http.StatusUnprocessableEntity, httperror.CodeIdempotencyKeyReused)
+	walletInDTO.Currency = ""
 
 	// add 2nd wallet
 	idempotencyKey = uuid.New().String()
@@ -52,48 +70,91 @@ func TestHappyPath(t *testing.T) {
 	depositDTO := deposit_money.DepositDTO{
 		IdempotencyKey: idempotencyKey,
 		WalletID:       fromWalletID,
-		Value:          100.53,
+		Value:          money.NewAmount(10053, 2),
 	}
 	err = depositMoney(&httpClient, depositDTO)
 	require.NoError(t, err)
 
-	// imitate duplicate deposit to 1st wallet
+	// imitate duplicate deposit to 1st wallet, the deposit is not applied twice
 	err = depositMoney(&httpClient, depositDTO)
-	require.Error(t, err)
+	require.NoError(t, err)
 
 	// transfer part of money from 1st wallet to 2nd wallet
 	idempotencyKey = uuid.New().String()
 	transferDTO := transfer_money.TransferDTO{
 		FromWalletID:   fromWalletID,
 		ToWalletID:     toWalletID,
-		Value:          50.51,
+		Value:          money.NewAmount(5051, 2),
 		IdempotencyKey: idempotencyKey,
 	}
-	err = transferMoney(&httpClient, transferDTO)
+	transferOutDTO, err := transferMoney(&httpClient, transferDTO)
+	require.NoError(t, err)
+
+	// imitate duplicate transfer, the transfer is not applied twice
+	replayedTransferOutDTO, err := transferMoney(&httpClient, transferDTO)
 	require.NoError(t, err)
+	require.Equal(t, transferOutDTO, replayedTransferOutDTO)
 
-	// imitate duplicate transfer
-	err = transferMoney(&httpClient, transferDTO)
-	require.Error(t, err)
+	// get both legs of the transfer
+	transaction, err := getTransaction(&httpClient, get_transaction.TransactionInDTO{TransactionID: transferOutDTO.TransactionID})
+	require.NoError(t, err)
+	require.Equal(t, "transfer", transaction.Type)
+	require.Len(t, transaction.Legs, 2)
+	require.Equal(t, fromWalletID, transaction.Legs[0].WalletID)
+	require.Equal(t, toWalletID, transaction.Legs[0].CounterpartyWalletID)
+	require.Equal(t, toWalletID, transaction.Legs[1].WalletID)
+	require.Equal(t, fromWalletID, transaction.Legs[1].CounterpartyWalletID)
 
 	// transfer part of money from 1st wallet to 2nd wallet
 	idempotencyKey = uuid.New().String()
 	transferDTO.IdempotencyKey = idempotencyKey
-	transferDTO.Value = 50
-	err = transferMoney(&httpClient, transferDTO)
+	transferDTO.Value = money.NewAmount(50, 0)
+	_, err = transferMoney(&httpClient, transferDTO)
 	require.NoError(t, err)
 
 	// transfer part of money from 1st wallet to 2nd wallet, but 1st wallet does not have enough money
 	idempotencyKey = uuid.New().String()
 	transferDTO.IdempotencyKey = idempotencyKey
-	err = transferMoney(&httpClient, transferDTO)
-	require.Error(t, err)
+	_, err = transferMoney(&httpClient, transferDTO)
+	requireErrorCode(t, err, http.StatusUnprocessableEntity, httperror.CodeInsufficientFunds)
+
+	// withdraw part of money from 2nd wallet
+	withdrawalDTO := withdraw_money.WithdrawalDTO{
+		IdempotencyKey: uuid.New().String(),
+		WalletID:       toWalletID,
+		Value:          money.NewAmount(51, 2),
+		Destination:    "iban:DE89370400440532013000",
+	}
+	err = withdrawMoney(&httpClient, withdrawalDTO)
+	require.NoError(t, err)
+
+	// withdraw more money than 2nd wallet has
+	withdrawalDTO.IdempotencyKey = uuid.New().String()
+	withdrawalDTO.Value = money.NewAmount(1000, 0)
+	err = withdrawMoney(&httpClient, withdrawalDTO)
+	requireErrorCode(t, err, http.StatusUnprocessableEntity, httperror.CodeInsufficientFunds)
+
+	// get balance of 1st wallet
+	balance, err := getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: fromWalletID})
+	require.NoError(t, err)
+	require.Equal(t, "0.02", balance.Balance.String())
+	require.Equal(t, money.USD, balance.Currency)
+
+	// get balance of 2nd wallet
+	balance, err = getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: toWalletID})
+	require.NoError(t, err)
+	require.Equal(t, "100.00", balance.Balance.String())
+
+	// get balance of unknown wallet
+	_, err = getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: -1})
+	requireErrorCode(t, err, http.StatusNotFound, httperror.CodeWalletNotFound)
 
 	// get income operation by 1st wallet
+	income, outcome := int8(0), int8(1)
 	filterDTO := get_operations.FilterDTO{
 		WalletID:  fromWalletID,
 		Date:      time.Now().UTC().Format("2006-01-02"),
-		Direction: 0,
+		Direction: &income,
 	}
 	operations, err := getOperations(&httpClient, filterDTO)
 	require.NoError(t, err)
@@ -107,7 +168,7 @@ func TestHappyPath(t *testing.T) {
 
 	// get outcome operation by 1st wallet
 	filterDTO.WalletID = fromWalletID
-	filterDTO.Direction = 1
+	filterDTO.Direction = &outcome
 	operations, err = getOperations(&httpClient, filterDTO)
 	require.NoError(t, err)
 	require.Len(t, operations, 3)
@@ -116,7 +177,361 @@ func TestHappyPath(t *testing.T) {
 	filterDTO.WalletID = toWalletID
 	operations, err = getOperations(&httpClient, filterDTO)
 	require.NoError(t, err)
-	require.Len(t, operations, 1)
+	require.Len(t, operations, 2)
+
+	// get all operations of 1st wallet by pages
+	filterDTO = get_operations.FilterDTO{WalletID: fromWalletID, Limit: 1}
+	operations, err = getOperations(&httpClient, filterDTO)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+
+	// get operations of 1st wallet in json
+	filterDTO = get_operations.FilterDTO{WalletID: fromWalletID, Format: get_operations.FormatJSON}
+	operationsOut, err := getOperationsJSON(&httpClient, filterDTO)
+	require.NoError(t, err)
+	require.Len(t, operationsOut.Operations, 3)
+	for i := 1; i < len(operationsOut.Operations); i++ {
+		previous, current := operationsOut.Operations[i-1], operationsOut.Operations[i]
+		require.NotEqual(t, previous.ID, current.ID)
+		require.False(t, current.CreatedAt.Before(previous.CreatedAt))
+	}
+}
+
+func TestHolds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	httpClient := http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	// add payer and merchant wallets
+	payer, err := addWallet(&httpClient, add_wallet.WalletInDTO{IdempotencyKey: uuid.New().String()})
+	require.NoError(t, err)
+	merchant, err := addWallet(&httpClient, add_wallet.WalletInDTO{IdempotencyKey: uuid.New().String()})
+	require.NoError(t, err)
+
+	err = depositMoney(&httpClient, deposit_money.DepositDTO{
+		IdempotencyKey: uuid.New().String(),
+		WalletID:       payer.WalletID,
+		Value:          money.NewAmount(10, 0),
+	})
+	require.NoError(t, err)
+
+	// reserve money of the payer
+	var hold authorize_hold.HoldOutDTO
+	err = post(&httpClient, "/authorizeHold", authorize_hold.AuthorizationDTO{
+		IdempotencyKey: uuid.New().String(),
+		FromWalletID:   payer.WalletID,
+		ToWalletID:     merchant.WalletID,
+		Value:          money.NewAmount(6, 0),
+	}, &hold)
+	require.NoError(t, err)
+	require.Equal(t, "authorized", hold.Status)
+
+	balance, err := getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: payer.WalletID})
+	require.NoError(t, err)
+	require.Equal(t, "10.00", balance.Balance.String())
+	require.Equal(t, "4.00", balance.AvailableBalance.String())
+
+	// held money can not be withdrawn
+	err = withdrawMoney(&httpClient, withdraw_money.WithdrawalDTO{
+		IdempotencyKey: uuid.New().String(),
+		WalletID:       payer.WalletID,
+		Value:          money.NewAmount(5, 0),
+		Destination:    "iban:DE89370400440532013000",
+	})
+	requireErrorCode(t, err, http.StatusUnprocessableEntity, httperror.CodeInsufficientFunds)
+
+	// capture part of the hold, the rest is released
+	err = post(&httpClient, "/captureHold", capture_hold.CaptureDTO{HoldID: hold.HoldID, Value: money.NewAmount(4, 0)}, &hold)
+	require.NoError(t, err)
+	require.Equal(t, "captured", hold.Status)
+
+	balance, err = getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: payer.WalletID})
+	require.NoError(t, err)
+	require.Equal(t, "6.00", balance.Balance.String())
+	require.Equal(t, "6.00", balance.AvailableBalance.String())
+
+	balance, err = getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: merchant.WalletID})
+	require.NoError(t, err)
+	require.Equal(t, "4.00", balance.Balance.String())
+
+	// captured hold can not be voided
+	err = post(&httpClient, "/voidHold", void_hold.VoidDTO{HoldID: hold.HoldID}, &hold)
+	requireErrorCode(t, err, http.StatusConflict, httperror.CodeHoldNotAuthorized)
+}
+
+func TestRefunds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	httpClient := http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	// pay the merchant
+	payer, err := addWallet(&httpClient, add_wallet.WalletInDTO{IdempotencyKey: uuid.New().String()})
+	require.NoError(t, err)
+	merchant, err := addWallet(&httpClient, add_wallet.WalletInDTO{IdempotencyKey: uuid.New().String()})
+	require.NoError(t, err)
+
+	err = depositMoney(&httpClient, deposit_money.DepositDTO{
+		IdempotencyKey: uuid.New().String(),
+		WalletID:       payer.WalletID,
+		Value:          money.NewAmount(10, 0),
+	})
+	require.NoError(t, err)
+
+	payment, err := transferMoney(&httpClient, transfer_money.TransferDTO{
+		IdempotencyKey: uuid.New().String(),
+		FromWalletID:   payer.WalletID,
+		ToWalletID:     merchant.WalletID,
+		Value:          money.NewAmount(10, 0),
+	})
+	require.NoError(t, err)
+
+	// refund a part of the payment
+	var refund refund_transaction.RefundOutDTO
+	err = post(&httpClient, "/refundTransaction", refund_transaction.RefundDTO{
+		IdempotencyKey: uuid.New().String(),
+		TransactionID:  payment.TransactionID,
+		Value:          money.NewAmount(4, 0),
+	}, &refund)
+	require.NoError(t, err)
+	require.Equal(t, payment.TransactionID, refund.OriginalTransactionID)
+
+	original, err := getTransaction(&httpClient, get_transaction.TransactionInDTO{TransactionID: payment.TransactionID})
+	require.NoError(t, err)
+	require.Equal(t, "partially_refunded", original.Status)
+	require.Equal(t, "4.00", original.RefundedValue.String())
+
+	// concurrent refunds never return more than the rest of the payment
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = post(&httpClient, "/refundTransaction", refund_transaction.RefundDTO{
+				IdempotencyKey: uuid.New().String(),
+				TransactionID:  payment.TransactionID,
+				Value:          money.NewAmount(2, 0),
+			}, &refund_transaction.RefundOutDTO{})
+		}(i)
+	}
+	wg.Wait()
+
+	refunded := 0
+	for _, err := range errs {
+		if err == nil {
+			refunded++
+			continue
+		}
+		requireErrorCode(t, err, http.StatusUnprocessableEntity, httperror.CodeRefundExceedsTransaction)
+	}
+	require.Equal(t, 3, refunded)
+
+	balance, err := getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: payer.WalletID})
+	require.NoError(t, err)
+	require.Equal(t, "10.00", balance.Balance.String())
+
+	original, err = getTransaction(&httpClient, get_transaction.TransactionInDTO{TransactionID: payment.TransactionID})
+	require.NoError(t, err)
+	require.Equal(t, "refunded", original.Status)
+
+	// refund operations reference the payment
+	operations, err := getOperationsJSON(&httpClient, get_operations.FilterDTO{
+		WalletID: payer.WalletID,
+		Format:   get_operations.FormatJSON,
+	})
+	require.NoError(t, err)
+	require.Len(t, operations.Operations, 6)
+	require.Equal(t, payment.TransactionID, operations.Operations[2].OriginalTransactionID)
+	require.Equal(t, refund.TransactionID, operations.Operations[2].TransactionID)
+	require.Equal(t, merchant.WalletID, operations.Operations[2].CounterpartyWalletID)
+
+	// deposits are not refundable
+	err = post(&httpClient, "/refundTransaction", refund_transaction.RefundDTO{
+		IdempotencyKey: uuid.New().String(),
+		TransactionID:  operations.Operations[0].TransactionID,
+	}, &refund)
+	requireErrorCode(t, err, http.StatusConflict, httperror.CodeNotRefundable)
+}
+
+func TestOppositeTransfers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	httpClient := http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	wallets := make([]add_wallet.WalletOutDTO, 2)
+	for i := range wallets {
+		var err error
+		wallets[i], err = addWallet(&httpClient, add_wallet.WalletInDTO{IdempotencyKey: uuid.New().String()})
+		require.NoError(t, err)
+
+		err = depositMoney(&httpClient, deposit_money.DepositDTO{
+			IdempotencyKey: uuid.New().String(),
+			WalletID:       wallets[i].WalletID,
+			Value:          money.NewAmount(100, 0),
+		})
+		require.NoError(t, err)
+	}
+
+	// transfers in both directions at once lock the wallets in the same order and never deadlock
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := wallets[i%2], wallets[(i+1)%2]
+			_, errs[i] = transferMoney(&httpClient, transfer_money.TransferDTO{
+				IdempotencyKey: uuid.New().String(),
+				FromWalletID:   from.WalletID,
+				ToWalletID:     to.WalletID,
+				Value:          money.NewAmount(1, 0),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	for _, w := range wallets {
+		balance, err := getBalance(&httpClient, get_balance.BalanceInDTO{WalletID: w.WalletID})
+		require.NoError(t, err)
+		require.Equal(t, "100.00", balance.Balance.String())
+	}
+}
+
+func TestValidation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	httpClient := http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	w, err := addWallet(&httpClient, add_wallet.WalletInDTO{IdempotencyKey: uuid.New().String()})
+	require.NoError(t, err)
+
+	// a negative self-transfer is rejected with both violations at once
+	_, err = transferMoney(&httpClient, transfer_money.TransferDTO{
+		IdempotencyKey: uuid.New().String(),
+		FromWalletID:   w.WalletID,
+		ToWalletID:     w.WalletID,
+		Value:          money.NewAmount(-1, 0),
+	})
+	requireErrorCode(t, err, http.StatusUnprocessableEntity, httperror.CodeValidationFailed)
+
+	var statusErr unsuccessStatusError
+	require.True(t, errors.As(err, &statusErr))
+	require.Equal(t, []httperror.ViolationDTO{
+		{Field: "value", Code: httperror.CodeInvalidAmount, Message: "invalid amount: -1 is not positive"},
+		{Field: "to_wallet_id", Code: httperror.CodeSameWallet, Message: fmt.Sprintf("source and destination wallets are the same: %d", w.WalletID)},
+	}, statusErr.response.Violations)
+
+	// a negative deposit does not act like a withdrawal
+	err = depositMoney(&httpClient, deposit_money.DepositDTO{
+		IdempotencyKey: uuid.New().String(),
+		WalletID:       w.WalletID,
+		Value:          money.NewAmount(-1, 0),
+	})
+	requireErrorCode(t, err, http.StatusBadRequest, httperror.CodeInvalidAmount)
+}
+
+type unsuccessStatusError struct {
+	statusCode int
+	response   httperror.ErrorDTO
+}
+
+func newUnsuccessStatusError(resp *http.Response) error {
+	err := unsuccessStatusError{statusCode: resp.StatusCode}
+	_ = json.NewDecoder(resp.Body).Decode(&err.response)
+	return err
+}
+
+func (e unsuccessStatusError) Error() string {
+	return fmt.Sprintf("unsuccess status code %d: %s", e.statusCode, e.response.Message)
+}
+
+func requireErrorCode(t *testing.T, err error, statusCode int, code string) {
+	t.Helper()
+	var statusErr unsuccessStatusError
+	require.True(t, errors.As(err, &statusErr), "got error %v", err)
+	require.Equal(t, statusCode, statusErr.statusCode)
+	require.Equal(t, code, statusErr.response.Code)
+}
+
+// post sends the JSON request to the path and decodes the JSON response into out.
+func post(client *http.Client, path string, in, out interface{}) error {
+	marshaled, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://localhost:8080"+path,
+		bytes.NewReader(marshaled),
+	)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newUnsuccessStatusError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func getBalance(client *http.Client, in get_balance.BalanceInDTO) (get_balance.BalanceOutDTO, error) {
+	marshaled, err := json.Marshal(in)
+	if err != nil {
+		return get_balance.BalanceOutDTO{}, err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://localhost:8080/getBalance",
+		bytes.NewReader(marshaled),
+	)
+	if err != nil {
+		return get_balance.BalanceOutDTO{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return get_balance.BalanceOutDTO{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return get_balance.BalanceOutDTO{}, newUnsuccessStatusError(resp)
+	}
+
+	var out get_balance.BalanceOutDTO
+	err = json.NewDecoder(resp.Body).Decode(&out)
+	if err != nil {
+		return get_balance.BalanceOutDTO{}, err
+	}
+
+	return out, nil
 }
 
 func getOperations(client *http.Client, in get_operations.FilterDTO) ([][]string, error) {
@@ -140,16 +555,48 @@ func getOperations(client *http.Client, in get_operations.FilterDTO) ([][]string
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unsuccess status code")
+		return nil, newUnsuccessStatusError(resp)
 	}
 
 	return csv.NewReader(resp.Body).ReadAll()
 }
 
-func transferMoney(client *http.Client, in transfer_money.TransferDTO) error {
+func getOperationsJSON(client *http.Client, in get_operations.FilterDTO) (get_operations.OperationsOutDTO, error) {
 	marshaled, err := json.Marshal(in)
 	if err != nil {
-		return err
+		return get_operations.OperationsOutDTO{}, err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://localhost:8080/getOperations",
+		bytes.NewReader(marshaled),
+	)
+	if err != nil {
+		return get_operations.OperationsOutDTO{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return get_operations.OperationsOutDTO{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return get_operations.OperationsOutDTO{}, newUnsuccessStatusError(resp)
+	}
+
+	var out get_operations.OperationsOutDTO
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return get_operations.OperationsOutDTO{}, err
+	}
+
+	return out, nil
+}
+
+func transferMoney(client *http.Client, in transfer_money.TransferDTO) (transfer_money.TransferOutDTO, error) {
+	marshaled, err := json.Marshal(in)
+	if err != nil {
+		return transfer_money.TransferOutDTO{}, err
 	}
 
 	req, err := http.NewRequest(
@@ -157,6 +604,70 @@ func transferMoney(client *http.Client, in transfer_money.TransferDTO) error {
 		"http://localhost:8080/transferMoney",
 		bytes.NewReader(marshaled),
 	)
+	if err != nil {
+		return transfer_money.TransferOutDTO{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return transfer_money.TransferOutDTO{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return transfer_money.TransferOutDTO{}, newUnsuccessStatusError(resp)
+	}
+
+	var out transfer_money.TransferOutDTO
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return transfer_money.TransferOutDTO{}, err
+	}
+
+	return out, nil
+}
+
+func getTransaction(client *http.Client, in get_transaction.TransactionInDTO) (get_transaction.TransactionOutDTO, error) {
+	marshaled, err := json.Marshal(in)
+	if err != nil {
+		return get_transaction.TransactionOutDTO{}, err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://localhost:8080/getTransaction",
+		bytes.NewReader(marshaled),
+	)
+	if err != nil {
+		return get_transaction.TransactionOutDTO{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return get_transaction.TransactionOutDTO{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return get_transaction.TransactionOutDTO{}, newUnsuccessStatusError(resp)
+	}
+
+	var out get_transaction.TransactionOutDTO
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return get_transaction.TransactionOutDTO{}, err
+	}
+
+	return out, nil
+}
+
+func withdrawMoney(client *http.Client, in withdraw_money.WithdrawalDTO) error {
+	marshaled, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://localhost:8080/withdrawMoney",
+		bytes.NewReader(marshaled),
+	)
 	if err != nil {
 		return err
 	}
@@ -167,7 +678,7 @@ func transferMoney(client *http.Client, in transfer_money.TransferDTO) error {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unsuccess status code")
+		return newUnsuccessStatusError(resp)
 	}
 
 	return nil
@@ -194,7 +705,7 @@ func depositMoney(client *http.Client, in deposit_money.DepositDTO) error {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unsuccess status code")
+		return newUnsuccessStatusError(resp)
 	}
 
 	return nil
@@ -221,7 +732,7 @@ func addWallet(client *http.Client, in add_wallet.WalletInDTO) (add_wallet.Walle
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return add_wallet.WalletOutDTO{}, fmt.Errorf("unsuccess status code")
+		return add_wallet.WalletOutDTO{}, newUnsuccessStatusError(resp)
 	}
 
 	var out add_wallet.WalletOutDTO